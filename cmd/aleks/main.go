@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+The aleks command retrieves data from the Aleks service.  The Client is
+configured by the environment variables described by
+aleks.NewClientFromEnv.  Usage:
+
+	aleks report [-from YYYY-MM-DD] [-to YYYY-MM-DD] [classcode...]
+	aleks parse [-mode lenient|strict|drop] [-tz location] [file...]
+	aleks methods [method...]
+
+The report subcommand writes the placement records for the provided
+class-codes, one JSON object per line, to the standard output.  The
+dates and class-codes default to the ALEKS_FROM_COMPLETION_DATE,
+ALEKS_TO_COMPLETION_DATE and ALEKS_CLASSCODES environment variables,
+as they were by the placementreport command that it replaces.
+
+The parse subcommand writes the placement records in the provided CSV
+formatted placement reports (or the standard input), such as those
+downloaded from the Aleks web interface, in the same format as the
+report subcommand.  The Client's environment variables aren't required.
+The mode determines how records with invalid values are handled - see
+aleks.ParseOptions - and the report's dates and times are interpreted
+in the tz location (e.g. America/New_York), which defaults to UTC.  The
+report subcommand uses the ALEKS_PARSE_MODE and ALEKS_TIMEZONE
+environment variables instead.
+
+The methods subcommand lists the XML-RPC methods provided by the Aleks
+service or, if methods are named, prints their signatures and
+documentation.
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// command is an aleks subcommand, which is passed the arguments that
+// follow its name and returns the process's exit status.
+type command func(ctx context.Context, args []string) int
+
+var commands = map[string]command{
+	"report":  report,
+	"parse":   parse,
+	"methods": methods,
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+	status := cmd(ctx, os.Args[2:])
+	cancel()
+	os.Exit(status)
+}
+
+func usage() {
+	fmt.Fprintln(flag.CommandLine.Output(), `Usage:
+  aleks report [-from YYYY-MM-DD] [-to YYYY-MM-DD] [classcode...]
+  aleks parse [-mode lenient|strict|drop] [-tz location] [file...]
+  aleks methods [method...]`)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PennState/aleks-client/pkg/aleks"
+)
+
+func methods(ctx context.Context, args []string) int {
+	client, err := aleks.NewClientFromEnv()
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+
+	if len(args) == 0 {
+		names, err := client.ListMethods(ctx)
+		if err != nil {
+			logIntrospectionError(err)
+			return 1
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return 0
+	}
+
+	status := 0
+	for _, name := range args {
+		sigs, err := client.MethodSignature(ctx, name)
+		if err != nil {
+			logIntrospectionError(err)
+			status = 1
+			continue
+		}
+		help, err := client.MethodHelp(ctx, name)
+		if err != nil {
+			logIntrospectionError(err)
+			status = 1
+			continue
+		}
+		fmt.Println(name)
+		for _, sig := range sigs {
+			if len(sig) == 0 {
+				continue
+			}
+			fmt.Printf("  %s(%s) %s\n", name, strings.Join(sig[1:], ", "), sig[0])
+		}
+		if help != "" {
+			fmt.Printf("  %s\n", strings.ReplaceAll(strings.TrimSpace(help), "\n", "\n  "))
+		}
+	}
+	return status
+}
+
+func logIntrospectionError(err error) {
+	if errors.Is(err, aleks.ErrIntrospectionUnsupported) {
+		log.Error("The Aleks service doesn't support XML-RPC introspection: ", err)
+		return
+	}
+	log.Error(err)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PennState/aleks-client/pkg/aleks"
+)
+
+func parse(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ExitOnError)
+	mode := fs.String("mode", aleks.ParseLenient.String(), "handling of invalid values (lenient, strict or drop)")
+	tz := fs.String("tz", "UTC", "time zone of the report's dates and times (e.g. America/New_York)")
+	_ = fs.Parse(args)
+	opts := aleks.ParseOptions{}
+	if err := opts.Mode.UnmarshalText([]byte(*mode)); err != nil {
+		log.Error(err)
+		return 2
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		log.Error(err)
+		return 2
+	}
+	opts.Location = loc
+
+	if fs.NArg() == 0 {
+		return parseReport(opts, os.Stdin, "-")
+	}
+	status := 0
+	for _, name := range fs.Args() {
+		if ctx.Err() != nil {
+			return 1
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			log.Error(err)
+			status = 1
+			continue
+		}
+		if s := parseReport(opts, f, name); s != 0 {
+			status = s
+		}
+		f.Close()
+	}
+	return status
+}
+
+// parseReport writes the placement records parsed from the provided
+// reader, one JSON object per line, to the standard output.
+func parseReport(opts aleks.ParseOptions, r io.Reader, name string) int {
+	pr, errs := opts.ParsePlacementReport(r)
+	logger := log.WithField("file", name)
+	for _, err := range errs {
+		logger.Error(err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, rec := range pr {
+		for _, w := range rec.Warnings {
+			logger.Warn(w)
+		}
+		if err := enc.Encode(rec); err != nil {
+			logger.Error(err)
+			return 1
+		}
+	}
+	logger.Info("Placement record count: ", len(pr))
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
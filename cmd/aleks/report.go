@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PennState/aleks-client/pkg/aleks"
+)
+
+func report(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	from := fs.String("from", os.Getenv("ALEKS_FROM_COMPLETION_DATE"), "first completion date (YYYY-MM-DD)")
+	to := fs.String("to", os.Getenv("ALEKS_TO_COMPLETION_DATE"), "last completion date (YYYY-MM-DD)")
+	_ = fs.Parse(args)
+	classcodes := fs.Args()
+	if len(classcodes) == 0 && os.Getenv("ALEKS_CLASSCODES") != "" {
+		classcodes = strings.Split(os.Getenv("ALEKS_CLASSCODES"), ",")
+	}
+	if len(classcodes) == 0 {
+		log.Error("At least one class-code is required")
+		return 2
+	}
+
+	client, err := aleks.NewClientFromEnv()
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	count := 0
+	errs := client.WalkPlacementReport(ctx, *from, *to, func(classcode string, page int, rec aleks.PlacementRecord) error {
+		count++
+		return enc.Encode(rec)
+	}, classcodes...)
+	for _, err := range errs {
+		log.Error(err)
+	}
+	log.Info("Placement record count: ", count)
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
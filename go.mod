@@ -5,7 +5,6 @@ go 1.13
 require (
 	github.com/PennState/proctor v0.3.0
 	github.com/kelseyhightower/envconfig v1.4.0
-	github.com/kolo/xmlrpc v0.0.0-20190909154602-56d5ec7c422e
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.4.0
 	golang.org/x/text v0.3.2 // indirect
@@ -17,11 +17,19 @@ limitations under the License.
 package aleks
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	stdurl "net/url"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -33,54 +41,389 @@ const (
 	// URL is not provided via either the parameterized NewClient
 	// constructor or the no-parameters NewClientFromEnv constructor.
 	AleksDefaultURL = "https://secure.aleks.com/xmlrpc"
+
+	// AleksDefaultMaxResponseSize is the maximum size, in bytes, of a
+	// response body unless an alternate value is provided via the
+	// WithMaxResponseSize option.
+	AleksDefaultMaxResponseSize = 64 << 20
+
+	// AleksDefaultUserAgent is sent in the User-Agent header of each
+	// XML-RPC call unless an alternate value is provided via the
+	// WithUserAgent option.
+	AleksDefaultUserAgent = "aleks-client"
+
+	// AleksDefaultMaxPages is the maximum number of pages retrieved for
+	// each class-code unless an alternate value is provided via the
+	// WithMaxPages option.
+	AleksDefaultMaxPages = 1000
 )
 
 // Client contains the basic XML-RPC parameters required to make a call
 // to the Aleks service.
 type Client struct {
-	url      string
-	username string
-	password string
-	trans    http.RoundTripper
+	url            string
+	username       string
+	password       string
+	base           http.RoundTripper
+	hc             *http.Client
+	timeout        time.Duration
+	tlsConfig      *tls.Config
+	maxRespSize    int64
+	userAgent      string
+	logger         log.FieldLogger
+	maxConcurrency int
+	retryPolicy    RetryPolicy
+	limiter        *rateLimiter
+	multicallSize  int
+	pageWindow     int
+	maxPages       int
+	parseOptions   ParseOptions
+
+	// multicallRejected is set (atomically) once the Aleks service has
+	// rejected a system.multicall call.
+	multicallRejected int32
+}
+
+// Option configures the optional behavior of a Client and is applied
+// by the NewClient and NewClientFromEnv constructors.
+type Option func(*Client) error
+
+// WithHTTPClient uses a copy of the provided http.Client to make
+// XML-RPC calls.  If the client's Transport is nil, the
+// http.DefaultTransport is used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		if hc == nil {
+			return errors.New("HTTP client must not be nil")
+		}
+		cp := *hc
+		c.hc = &cp
+		c.base = hc.Transport
+		if c.base == nil {
+			c.base = http.DefaultTransport
+		}
+		return nil
+	}
+}
+
+// WithTransport uses the provided http.RoundTripper, in place of the
+// default transport (which has compression disabled), to make XML-RPC
+// calls.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) error {
+		if rt == nil {
+			return errors.New("transport must not be nil")
+		}
+		c.base = rt
+		return nil
+	}
+}
+
+// WithTimeout limits the time each XML-RPC call attempt may take,
+// including reading the response.  An attempt that times out is
+// retried according to the Client's RetryPolicy.  The default (zero)
+// allows calls to take as long as the context they're made with
+// permits.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		if d < 0 {
+			return errors.New("timeout must not be negative")
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithLogger replaces the standard logrus logger used by the Client.
+func WithLogger(logger log.FieldLogger) Option {
+	return func(c *Client) error {
+		if logger == nil {
+			return errors.New("logger must not be nil")
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithUserAgent replaces the AleksDefaultUserAgent sent with each
+// XML-RPC call.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		if ua == "" {
+			return errors.New("user agent must not be empty")
+		}
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// WithMaxResponseSize replaces the AleksDefaultMaxResponseSize.  Calls
+// whose response bodies exceed the provided number of bytes fail with
+// an error that wraps ErrResponseTooLarge.  A size of zero removes the
+// limit.
+func WithMaxResponseSize(size int64) Option {
+	return func(c *Client) error {
+		if size < 0 {
+			return errors.New("maximum response size must not be negative")
+		}
+		c.maxRespSize = size
+		return nil
+	}
+}
+
+// WithMaxConcurrency limits the number of class-codes whose data is
+// retrieved concurrently.  The default (zero) retrieves the data for
+// every requested class-code concurrently.
+func WithMaxConcurrency(n int) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("maximum concurrency must not be negative")
+		}
+		c.maxConcurrency = n
+		return nil
+	}
+}
+
+// WithRetryPolicy replaces the DefaultRetryPolicy used to retry
+// transient XML-RPC failures.  Retries are disabled by a policy with a
+// MaxAttempts value of one.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) error {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		c.retryPolicy = p
+		return nil
+	}
+}
+
+// WithRateLimit limits the rate at which XML-RPC calls are made to the
+// Aleks service to the provided number of calls per second, with
+// bursts of up to burst calls.  The limit is shared by all of the
+// Client's threads, regardless of the number of class-codes requested,
+// and counts method calls rather than HTTP requests - each of the calls
+// batched by WithMulticall counts towards the limit.  A rate of zero
+// (the default) disables rate limiting.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(c *Client) error {
+		if rate < 0 {
+			return errors.New("rate limit must not be negative")
+		}
+		if rate == 0 {
+			c.limiter = nil
+			return nil
+		}
+		if burst < 1 {
+			return errors.New("rate limit burst must be at least one")
+		}
+		c.limiter = newRateLimiter(rate, burst)
+		return nil
+	}
+}
+
+// WithMulticall batches the requests for up to size consecutive pages
+// of a class-code's placement report into a single system.multicall
+// call, reducing the number of round trips made for large reports.
+// Pages past the last are discarded.  If the Aleks service rejects the
+// system.multicall method, the Client falls back to requesting each
+// page individually for the rest of its life.  Each batched page counts
+// towards the WithRateLimit limit.  The default (zero) requests each
+// page individually.
+func WithMulticall(size int) Option {
+	return func(c *Client) error {
+		if size < 0 {
+			return errors.New("multicall batch size must not be negative")
+		}
+		c.multicallSize = size
+		return nil
+	}
+}
+
+// WithPageConcurrency speculatively requests up to n pages of a
+// class-code's placement report concurrently, rather than one at a
+// time, to reduce the time taken by class-codes with many pages.  The
+// records are still returned in page order and the pages requested past
+// the last are discarded (and their requests cancelled once the last
+// page is known).  When combined with WithMulticall, up to n batches of
+// pages are requested concurrently.  Note that this multiplies the
+// number of concurrent requests allowed by WithMaxConcurrency.  The
+// default (zero) requests one page at a time.
+func WithPageConcurrency(n int) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("page concurrency must not be negative")
+		}
+		c.pageWindow = n
+		return nil
+	}
+}
+
+// WithMaxPages replaces the AleksDefaultMaxPages.  The retrieval of a
+// class-code's placement report stops with a PaginationError if the
+// class-code has more than the provided number of pages.  A limit of
+// zero removes the limit, although the retrieval of a class-code's
+// placement report still stops if a page is identical to the previous
+// page.
+func WithMaxPages(n int) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("maximum page count must not be negative")
+		}
+		c.maxPages = n
+		return nil
+	}
+}
+
+// WithParseOptions determines how the values in the placement report
+// pages are parsed.  By default, values are parsed leniently.  If the
+// options' Location is nil, the Location provided by the WithLocation
+// option (if any) is retained.
+func WithParseOptions(o ParseOptions) Option {
+	return func(c *Client) error {
+		if err := o.validate(); err != nil {
+			return err
+		}
+		if o.Location == nil {
+			o.Location = c.parseOptions.Location
+		}
+		c.parseOptions = o
+		return nil
+	}
 }
 
-// NewClient returns a new Aleks client given an optional URL and a
-// required username and password.
-func NewClient(url, username, password string) (*Client, error) {
-	rt := RoundTripper{
-		Trans: transport(),
+// WithLocation sets the time zone in which the dates and times in the
+// placement report pages are interpreted, which should be the local
+// time zone of the Aleks service.  The default is UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(c *Client) error {
+		if loc == nil {
+			return errors.New("location must not be nil")
+		}
+		c.parseOptions.Location = loc
+		return nil
 	}
-	return newClient(url, username, password, &rt)
+}
+
+// NewClient returns a new Aleks client given an optional URL, a
+// required username and password and zero or more options.
+func NewClient(url, username, password string, opts ...Option) (*Client, error) {
+	return newClient(url, username, password, opts...)
 }
 
 type clientEnvConfig struct {
-	URL      string
-	Username string `required:"true"`
-	Password string `required:"true"`
+	URL            string
+	Username       string `required:"true"`
+	Password       string `required:"true"`
+	Timeout        time.Duration
+	UserAgent      string   `split_words:"true"`
+	MaxRespSize    int64    `envconfig:"MAX_RESPONSE_SIZE"`
+	MaxConcurrency int      `split_words:"true"`
+	MaxAttempts    int      `split_words:"true"`
+	RateLimit      float64  `split_words:"true"`
+	RateBurst      int      `split_words:"true" default:"1"`
+	CAFile         string   `envconfig:"CA_FILE"`
+	ClientCert     string   `split_words:"true"`
+	ClientKey      string   `split_words:"true"`
+	PinnedSHA256   []string `envconfig:"PINNED_SHA256"`
+	TLSMinVersion  string   `envconfig:"TLS_MIN_VERSION"`
+	Multicall      int
+	PageWindow     int       `envconfig:"PAGE_CONCURRENCY"`
+	MaxPages       int       `split_words:"true" default:"1000"`
+	ParseMode      ParseMode `split_words:"true"`
+	Timezone       string
+}
+
+// options returns the Options described by the environment variables
+// that were set.
+func (cfg clientEnvConfig) options() ([]Option, error) {
+	opts := []Option{
+		WithTimeout(cfg.Timeout),
+		WithMaxConcurrency(cfg.MaxConcurrency),
+		WithRateLimit(cfg.RateLimit, cfg.RateBurst),
+		WithMulticall(cfg.Multicall),
+		WithPageConcurrency(cfg.PageWindow),
+		WithMaxPages(cfg.MaxPages),
+		WithParseOptions(ParseOptions{Mode: cfg.ParseMode}),
+	}
+	if cfg.MaxRespSize != 0 {
+		opts = append(opts, WithMaxResponseSize(cfg.MaxRespSize))
+	}
+	if cfg.UserAgent != "" {
+		opts = append(opts, WithUserAgent(cfg.UserAgent))
+	}
+	if cfg.MaxAttempts != 0 {
+		p := DefaultRetryPolicy
+		p.MaxAttempts = cfg.MaxAttempts
+		opts = append(opts, WithRetryPolicy(p))
+	}
+	if cfg.CAFile != "" {
+		opts = append(opts, WithCAFile(cfg.CAFile))
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		opts = append(opts, WithClientCertificateFiles(cfg.ClientCert, cfg.ClientKey))
+	}
+	if len(cfg.PinnedSHA256) > 0 {
+		opts = append(opts, WithPinnedSHA256(cfg.PinnedSHA256...))
+	}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithLocation(loc))
+	}
+	if cfg.TLSMinVersion != "" {
+		v, err := parseTLSVersion(cfg.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithMinTLSVersion(v))
+	}
+	return opts, nil
 }
 
 // NewClientFromEnv returns a new Aleks client from environment variables
 // as follows:
 //
-//   - ALEKS_URL      (Optional - see the default in constants)
-//   - ALEKS_USERNAME (Required)
-//   - ALEKS_PASSWORD (Required)
+//   - ALEKS_URL               (Optional - see the default in constants)
+//   - ALEKS_USERNAME          (Required)
+//   - ALEKS_PASSWORD          (Required)
+//   - ALEKS_TIMEOUT           (Optional - e.g. 30s, see WithTimeout)
+//   - ALEKS_USER_AGENT        (Optional - see WithUserAgent)
+//   - ALEKS_MAX_RESPONSE_SIZE (Optional - bytes, see WithMaxResponseSize)
+//   - ALEKS_MAX_CONCURRENCY   (Optional - see WithMaxConcurrency)
+//   - ALEKS_MAX_ATTEMPTS      (Optional - see RetryPolicy)
+//   - ALEKS_RATE_LIMIT        (Optional - requests/second, see WithRateLimit)
+//   - ALEKS_RATE_BURST        (Optional - defaults to 1)
+//   - ALEKS_CA_FILE           (Optional - see WithCAFile)
+//   - ALEKS_CLIENT_CERT       (Optional - PEM file, requires ALEKS_CLIENT_KEY)
+//   - ALEKS_CLIENT_KEY        (Optional - PEM file, requires ALEKS_CLIENT_CERT)
+//   - ALEKS_PINNED_SHA256     (Optional - comma separated, see WithPinnedSHA256)
+//   - ALEKS_TLS_MIN_VERSION   (Optional - 1.0, 1.1, 1.2 or 1.3)
+//   - ALEKS_MULTICALL         (Optional - pages per call, see WithMulticall)
+//   - ALEKS_PAGE_CONCURRENCY  (Optional - see WithPageConcurrency)
+//   - ALEKS_MAX_PAGES         (Optional - defaults to 1000, see WithMaxPages)
+//   - ALEKS_PARSE_MODE        (Optional - lenient, strict or drop)
+//   - ALEKS_TIMEZONE          (Optional - e.g. America/New_York, see WithLocation)
 //
-// It is important to note that the individual Aleks XMLRPC calls will
-// generally required additional parameters.
-func NewClientFromEnv() (*Client, error) {
+// Options derived from the environment are applied before the provided
+// options.  It is important to note that the individual Aleks XMLRPC
+// calls will generally required additional parameters.
+func NewClientFromEnv(opts ...Option) (*Client, error) {
 	cfg := clientEnvConfig{}
 	err := envconfig.Process(AleksEnvconfigPrefix, &cfg)
 	if err != nil {
 		return nil, err
 	}
-	rt := RoundTripper{
-		Trans: transport(),
+	envOpts, err := cfg.options()
+	if err != nil {
+		return nil, err
 	}
-	return newClient(cfg.URL, cfg.Username, cfg.Password, &rt)
+	opts = append(envOpts, opts...)
+	return newClient(cfg.URL, cfg.Username, cfg.Password, opts...)
 }
 
-func newClient(url, username, password string, trans http.RoundTripper) (*Client, error) {
+func newClient(url, username, password string, opts ...Option) (*Client, error) {
 	if url == "" {
 		url = AleksDefaultURL
 	}
@@ -91,10 +434,145 @@ func newClient(url, username, password string, trans http.RoundTripper) (*Client
 	if username == "" || password == "" {
 		return nil, errors.New("username and password parameters are both required")
 	}
-	return &Client{
-		url:      url,
-		username: username,
-		password: password,
-		trans:    trans,
-	}, nil
+	c := &Client{
+		url:         url,
+		username:    username,
+		password:    password,
+		base:        transport(),
+		maxRespSize: AleksDefaultMaxResponseSize,
+		userAgent:   AleksDefaultUserAgent,
+		maxPages:    AleksDefaultMaxPages,
+		logger:      log.StandardLogger(),
+		retryPolicy: DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	base, err := c.applyTLSConfig(c.base)
+	if err != nil {
+		return nil, err
+	}
+	if c.hc == nil {
+		c.hc = &http.Client{}
+	}
+	c.hc.Transport = base
+	return c, nil
+}
+
+// workerCount returns the number of threads used to retrieve the data
+// for the requested number of class-codes.
+func (c *Client) workerCount(classcodes int) int {
+	if c.maxConcurrency > 0 && c.maxConcurrency < classcodes {
+		return c.maxConcurrency
+	}
+	return classcodes
+}
+
+// Call invokes the named Aleks XML-RPC method with the provided
+// parameters (to which the Client's username and password are added)
+// and decodes the method's response into result, which should be a
+// pointer to a value of the type returned by the method.  Calls are
+// subject to the Client's rate limit and are aborted when the provided
+// context is done.  Faults returned by the Aleks service are reported
+// as an *AleksFault.  As the method may not be idempotent, a failed
+// call isn't retried - CallIdempotent should be used for methods that
+// can safely be repeated.
+func (c *Client) Call(ctx context.Context, method string, params map[string]string, result interface{}) error {
+	return c.attempt(ctx, method, c.withCredentials(params), result)
+}
+
+// CallIdempotent behaves like Call but retries failed calls according
+// to the Client's RetryPolicy, which may result in the method being
+// invoked more than once (e.g. when a response is lost).  It must only
+// be used for methods that have no effect other than returning a
+// result.
+func (c *Client) CallIdempotent(ctx context.Context, method string, params map[string]string, result interface{}) error {
+	_, err := c.call(ctx, method, c.withCredentials(params), result)
+	return err
+}
+
+// withCredentials returns a copy of the provided parameters to which
+// the Client's username and password have been added.
+func (c *Client) withCredentials(params map[string]string) map[string]string {
+	p := make(map[string]string, len(params)+2)
+	for k, v := range params {
+		p[k] = v
+	}
+	p["username"] = c.username
+	p["password"] = c.password
+	return p
+}
+
+// call makes an XML-RPC call, subject to the Client's rate limit and
+// RetryPolicy, and returns the number of attempts that were made along
+// with the error from the last attempt (if any).
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) (int, error) {
+	return c.callN(ctx, method, params, result, 1)
+}
+
+// callN behaves like call but each attempt consumes n of the rate
+// limit's tokens, as it makes n method calls (e.g. system.multicall).
+func (c *Client) callN(ctx context.Context, method string, params interface{}, result interface{}, n int) (int, error) {
+	return c.retryPolicy.do(ctx, func() error {
+		if err := c.limiter.waitN(ctx, n); err != nil {
+			return err
+		}
+		return c.invoke(ctx, method, params, result)
+	})
+}
+
+// attempt makes a single XML-RPC call subject to the Client's rate
+// limit.
+func (c *Client) attempt(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if err := c.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return c.invoke(ctx, method, params, result)
+}
+
+// invoke makes a single XML-RPC call.  A nil params value results in a
+// call without parameters.
+func (c *Client) invoke(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	body := bytes.Buffer{}
+	ps := []interface{}{}
+	if params != nil {
+		ps = append(ps, params)
+	}
+	if err := encodeMethodCall(&body, method, ps...); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set("Accept", "text/xml")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var r io.Reader = resp.Body
+	if c.maxRespSize > 0 {
+		if resp.ContentLength > c.maxRespSize {
+			return fmt.Errorf("%w: %d byte limit, %d byte Content-Length", ErrResponseTooLarge, c.maxRespSize, resp.ContentLength)
+		}
+		r = &maxSizeReader{r: r, remaining: c.maxRespSize, limit: c.maxRespSize}
+	}
+	return decodeMethodResponse(r, result)
 }
@@ -0,0 +1,223 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCall(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		if mc.MethodName != "getOtherReport" {
+			writeTestFault(w, -32601, "Method not found")
+			return
+		}
+		if mc.param("username") != "username" || mc.param("password") != "password" {
+			writeTestFault(w, 1, "Invalid username or password")
+			return
+		}
+		writeTestResponse(w, "Report for "+mc.param("class_code"))
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	params := map[string]string{"class_code": "ABCDE-FGHIJ"}
+	res := ""
+	require.NoError(t, c.Call(context.Background(), "getOtherReport", params, &res))
+	assert.Equal(t, "Report for ABCDE-FGHIJ", res)
+	assert.Equal(t, map[string]string{"class_code": "ABCDE-FGHIJ"}, params)
+
+	err = c.Call(context.Background(), "getUnknownReport", params, &res)
+	var fault *AleksFault
+	require.True(t, errors.As(err, &fault), err.Error())
+	assert.Equal(t, -32601, fault.Code)
+	assert.Equal(t, "Method not found", fault.Message)
+}
+
+func TestCallRetries(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeTestResponse(w, "ok")
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Multiplier: 1}))
+	require.NoError(t, err)
+
+	// Call makes a single attempt
+	res := ""
+	var statusErr *StatusError
+	err = c.Call(context.Background(), "submitResults", nil, &res)
+	require.True(t, errors.As(err, &statusErr), err)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+
+	// CallIdempotent retries
+	require.NoError(t, c.CallIdempotent(context.Background(), "getOtherReport", nil, &res))
+	assert.Equal(t, "ok", res)
+	mu.Lock()
+	assert.Equal(t, 3, calls)
+	mu.Unlock()
+}
+
+func TestClientOptions(t *testing.T) {
+	var mu sync.Mutex
+	var ua string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		mu.Lock()
+		ua = r.Header.Get("User-Agent")
+		mu.Unlock()
+		if mc.param("page_num") == "2" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		writeTestResponse(w, testPlacementReportPageFor(mc))
+	})
+	defer srv.Close()
+
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(log.DebugLevel)
+	rrt := countingRoundTripper{trans: http.DefaultTransport}
+	policy := RetryPolicy{MaxAttempts: 1, Multiplier: 1}
+	c, err := NewClient(srv.URL, "username", "password",
+		WithTransport(&rrt),
+		WithTimeout(50*time.Millisecond),
+		WithUserAgent("nightly-loader"),
+		WithLogger(logger),
+		WithRetryPolicy(policy),
+	)
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, pr, 1)
+	require.Len(t, errs, 1)
+	var netErr net.Error
+	require.True(t, errors.As(errs[0], &netErr), errs[0].Error())
+	assert.True(t, netErr.Timeout())
+	mu.Lock()
+	assert.Equal(t, "nightly-loader", ua)
+	mu.Unlock()
+	assert.Equal(t, 2, rrt.count)
+
+	require.NotEmpty(t, hook.AllEntries())
+	assert.Equal(t, "ABCDE-FGHIJ", hook.AllEntries()[0].Data["class_code"])
+}
+
+func TestClientOptionsValidation(t *testing.T) {
+	opts := []Option{
+		WithHTTPClient(nil),
+		WithTransport(nil),
+		WithTimeout(-time.Second),
+		WithLogger(nil),
+		WithUserAgent(""),
+		WithMaxResponseSize(-1),
+		WithMaxConcurrency(-1),
+		WithRetryPolicy(RetryPolicy{}),
+		WithRateLimit(-1, 1),
+		WithRateLimit(1, 0),
+		WithMulticall(-1),
+		WithPageConcurrency(-1),
+		WithMaxPages(-1),
+		WithParseOptions(ParseOptions{Mode: -1}),
+		WithParseOptions(ParseOptions{Columns: map[string]ParseMode{"Email": -1}}),
+		WithParseOptions(ParseOptions{Columns: map[string]ParseMode{"Placement Result %": ParseStrict}}),
+		WithParseOptions(ParseOptions{Columns: map[string]ParseMode{"Start Date/Start Tme": ParseStrict}}),
+		WithParseOptions(ParseOptions{Columns: map[string]ParseMode{"Email": ParseStrict, "EMAIL": ParseDrop}}),
+	}
+	for _, opt := range opts {
+		_, err := NewClient("", "username", "password", opt)
+		assert.Error(t, err)
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	env := map[string]string{
+		"ALEKS_URL":              "https://example.com/xmlrpc",
+		"ALEKS_USERNAME":         "username",
+		"ALEKS_PASSWORD":         "password",
+		"ALEKS_TIMEOUT":          "30s",
+		"ALEKS_USER_AGENT":       "nightly-loader",
+		"ALEKS_MAX_CONCURRENCY":  "4",
+		"ALEKS_MAX_ATTEMPTS":     "5",
+		"ALEKS_RATE_LIMIT":       "2.5",
+		"ALEKS_TLS_MIN_VERSION":  "1.2",
+		"ALEKS_MULTICALL":        "5",
+		"ALEKS_PAGE_CONCURRENCY": "3",
+		"ALEKS_MAX_PAGES":        "50",
+		"ALEKS_PARSE_MODE":       "drop",
+		"ALEKS_TIMEZONE":         "America/New_York",
+	}
+	for k, v := range env {
+		require.NoError(t, os.Setenv(k, v))
+		defer os.Unsetenv(k)
+	}
+
+	c, err := NewClientFromEnv(WithMaxConcurrency(8), WithTLSConfig(&tls.Config{ServerName: "example.com"})) //nolint:gosec
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/xmlrpc", c.url)
+	assert.Equal(t, 30*time.Second, c.timeout)
+	assert.Equal(t, "nightly-loader", c.userAgent)
+	assert.Equal(t, 8, c.maxConcurrency)
+	assert.Equal(t, 5, c.retryPolicy.MaxAttempts)
+	require.NotNil(t, c.limiter)
+	assert.Equal(t, 2.5, c.limiter.rate)
+	assert.Equal(t, float64(1), c.limiter.burst)
+	require.NotNil(t, c.tlsConfig)
+	assert.Equal(t, uint16(tls.VersionTLS12), c.tlsConfig.MinVersion)
+	assert.Equal(t, "example.com", c.tlsConfig.ServerName)
+	assert.Equal(t, 5, c.multicallSize)
+	assert.Equal(t, 3, c.pageWindow)
+	assert.Equal(t, 50, c.maxPages)
+	assert.Equal(t, ParseDrop, c.parseOptions.Mode)
+	require.NotNil(t, c.parseOptions.Location)
+	assert.Equal(t, "America/New_York", c.parseOptions.Location.String())
+}
+
+type countingRoundTripper struct {
+	mu    sync.Mutex
+	count int
+	trans http.RoundTripper
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.count++
+	rt.mu.Unlock()
+	return rt.trans.RoundTrip(req)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// faultCodeMethodNotFound is the standard XML-RPC fault code for an
+	// unknown method (see the XML-RPC specification for fault code
+	// interoperability).
+	faultCodeMethodNotFound = -32601
+)
+
+// faultMessages classifies faults by their messages.  The Aleks service
+// doesn't document its fault codes or messages, so these patterns are a
+// best guess based on the parameter names of the getPlacementReport
+// method rather than on faults captured from the service.  They're
+// anchored and must match the whole message so that an unrelated
+// fault, which merely mentions a password or a date, isn't classified
+// (in particular as ErrAuthentication, which aborts every class-code of
+// a placement report).
+var faultMessages = []struct {
+	re  *regexp.Regexp
+	err error
+}{
+	{regexp.MustCompile(`(?i)^method not found\b`), ErrMethodNotFound},
+	{regexp.MustCompile(`(?i)^invalid username or password$`), ErrAuthentication},
+	{regexp.MustCompile(`(?i)^invalid class code(:|$)`), ErrUnknownClasscode},
+	{regexp.MustCompile(`(?i)^invalid (from|to)_completion_date(:|$)`), ErrInvalidDateRange},
+}
+
+var (
+	// ErrAuthentication indicates that the Aleks service rejected the
+	// Client's username or password.
+	ErrAuthentication = errors.New("aleks rejected the username or password")
+
+	// ErrUnknownClasscode indicates that the Aleks service doesn't
+	// recognize a requested class-code.
+	ErrUnknownClasscode = errors.New("aleks doesn't recognize the class code")
+
+	// ErrInvalidDateRange indicates that the Aleks service rejected the
+	// requested completion dates.
+	ErrInvalidDateRange = errors.New("aleks rejected the completion date range")
+
+	// ErrMethodNotFound indicates that the Aleks service doesn't provide
+	// the requested XML-RPC method.
+	ErrMethodNotFound = errors.New("aleks doesn't provide the method")
+)
+
+// AleksFault is an XML-RPC fault returned by the Aleks service in place
+// of a method's result.  Faults that can be classified are also
+// equivalent to one of the ErrAuthentication, ErrUnknownClasscode,
+// ErrInvalidDateRange or ErrMethodNotFound sentinel errors when tested
+// with errors.Is.  These errors are generally caused by the Client's
+// configuration or the request's parameters and, unlike transient
+// failures, won't be resolved by repeating the call.
+type AleksFault struct {
+	Code    int
+	Message string
+}
+
+// Error implements the error interface.
+func (f *AleksFault) Error() string {
+	return fmt.Sprintf("Aleks fault %d: %s", f.Code, f.Message)
+}
+
+// Is reports whether the fault is classified as the target sentinel
+// error.  Other than the standard XML-RPC "method not found" code, the
+// Aleks service's fault codes aren't documented so faults are
+// classified by matching their whole messages against those that the
+// service is expected to return.  Unclassified faults match none of the
+// sentinel errors.
+func (f *AleksFault) Is(target error) bool {
+	return target != nil && f.sentinel() == target
+}
+
+func (f *AleksFault) sentinel() error {
+	if f.Code == faultCodeMethodNotFound {
+		return ErrMethodNotFound
+	}
+	msg := strings.TrimSpace(f.Message)
+	for _, m := range faultMessages {
+		if m.re.MatchString(msg) {
+			return m.err
+		}
+	}
+	return nil
+}
+
+// StatusError is returned when the Aleks service responds to an
+// XML-RPC call with an unsuccessful HTTP status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return "unexpected HTTP status: " + e.Status
+}
+
+// RecordError describes a placement report value that could not be
+// parsed or validated.  Each field other than Err is optional and is
+// only populated when the corresponding detail is known - for example,
+// an error in a header row has no StudentID.  Line is the one-based
+// number of the CSV record within its page where the header row is
+// line one.  The underlying error is available via errors.Unwrap or
+// errors.As.
+type RecordError struct {
+	Classcode string
+	Page      int
+	Line      int
+	StudentID string
+	Column    string
+	Value     string
+	Err       error
+}
+
+// Error implements the error interface.
+func (e *RecordError) Error() string {
+	ctx := []string{}
+	if e.Classcode != "" {
+		ctx = append(ctx, "class code "+e.Classcode)
+	}
+	if e.Page > 0 {
+		ctx = append(ctx, fmt.Sprintf("page %d", e.Page))
+	}
+	if e.Line > 0 {
+		ctx = append(ctx, fmt.Sprintf("line %d", e.Line))
+	}
+	if e.StudentID != "" {
+		ctx = append(ctx, "student "+e.StudentID)
+	}
+	if e.Column != "" {
+		ctx = append(ctx, fmt.Sprintf("column %q", e.Column))
+	}
+	if e.Value != "" {
+		ctx = append(ctx, fmt.Sprintf("value %q", e.Value))
+	}
+	if len(ctx) == 0 {
+		return e.Err.Error()
+	}
+	return strings.Join(ctx, ", ") + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// field returns a copy of the RecordError describing the provided
+// column and value.
+func (e RecordError) field(column, value string) RecordError {
+	e.Column = column
+	e.Value = value
+	return e
+}
+
+// append sets the RecordError's underlying error and appends it to the
+// provided errors.
+func (e RecordError) append(errs []error, err error) []error {
+	e.Err = err
+	return append(errs, &e)
+}
+
+// MarshalText returns the error's message so that RecordErrors, such as
+// a PlacementRecord's Warnings, are readable when encoded as JSON.
+func (e *RecordError) MarshalText() ([]byte, error) {
+	return []byte(e.Error()), nil
+}
+
+// ErrRecordDropped is the cause of the RecordError that is returned,
+// in place of its parse errors, for each record discarded by
+// ParseDrop.
+var ErrRecordDropped = errors.New("record dropped")
+
+// ErrPaginationAborted is matched (via errors.Is) by every
+// PaginationError.
+var ErrPaginationAborted = errors.New("pagination aborted")
+
+// ErrTooManyPages is the cause of a PaginationError when a class-code's
+// placement report has more pages than allowed by the WithMaxPages
+// option.
+var ErrTooManyPages = errors.New("too many pages")
+
+// ErrRepeatedPage is the cause of a PaginationError when a page of a
+// class-code's placement report is identical to the previous page.
+var ErrRepeatedPage = errors.New("page is identical to the previous page")
+
+// PaginationError is returned when the retrieval of a class-code's
+// placement report is stopped because it doesn't appear to end.  Page
+// is the one-based number of the page at which it was stopped.  The
+// cause, which wraps ErrTooManyPages or ErrRepeatedPage, is available
+// via errors.Unwrap or errors.As.
+type PaginationError struct {
+	Classcode string
+	Page      int
+	Err       error
+}
+
+// Error implements the error interface.
+func (e *PaginationError) Error() string {
+	return fmt.Sprintf("placement report for class code %s %s at page %d: %s", e.Classcode, ErrPaginationAborted, e.Page, e.Err)
+}
+
+// Unwrap returns the cause of the PaginationError.
+func (e *PaginationError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is ErrPaginationAborted.
+func (e *PaginationError) Is(target error) bool {
+	return target == ErrPaginationAborted
+}
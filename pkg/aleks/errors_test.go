@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAleksFaultSentinels(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Fault    *AleksFault
+		Sentinel error
+	}{
+		{"Authentication", &AleksFault{1, "Invalid username or password"}, ErrAuthentication},
+		{"Unknown class code", &AleksFault{2, "Invalid class code: ABCDE-FGHIJ"}, ErrUnknownClasscode},
+		{"Invalid date range", &AleksFault{3, "Invalid to_completion_date: 2016-13-01"}, ErrInvalidDateRange},
+		{"Method not found", &AleksFault{-32601, "Unknown method"}, ErrMethodNotFound},
+		{"Unclassified", &AleksFault{99, "Try again later"}, nil},
+		{"Authentication message", &AleksFault{0, "Invalid username or password"}, ErrAuthentication},
+		{"Unknown class code message", &AleksFault{0, "Invalid class code: ABCDE-FGHIJ"}, ErrUnknownClasscode},
+		{"Invalid date range message", &AleksFault{0, "Invalid from_completion_date: 2016-13-01"}, ErrInvalidDateRange},
+		{"Method not found message", &AleksFault{0, "Method not found: getReport"}, ErrMethodNotFound},
+		{"Update", &AleksFault{99, "Service update in progress"}, nil},
+		{"Validate", &AleksFault{99, "Unable to validate request"}, nil},
+		{"Password mention", &AleksFault{99, "Password rotation scheduled, try again later"}, nil},
+		{"Username mention", &AleksFault{99, "Too many requests for username"}, nil},
+		{"Class code mention", &AleksFault{99, "Report for class code ABCDE-FGHIJ is being generated"}, nil},
+		{"Embedded phrase", &AleksFault{99, "Upstream error: Invalid username or password"}, nil},
+		{"Code alone", &AleksFault{1, "Service update in progress"}, nil},
+		{"Other code alone", &AleksFault{2, "Try again later"}, nil},
+	}
+	sentinels := []error{ErrAuthentication, ErrUnknownClasscode, ErrInvalidDateRange, ErrMethodNotFound}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", test.Fault)
+			for _, sentinel := range sentinels {
+				assert.Equal(t, sentinel == test.Sentinel, errors.Is(err, sentinel), sentinel.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	listMethodsMethod     = "system.listMethods"
+	methodHelpMethod      = "system.methodHelp"
+	methodSignatureMethod = "system.methodSignature"
+)
+
+// ErrIntrospectionUnsupported is returned by the ListMethods, MethodHelp
+// and MethodSignature methods when the Aleks service doesn't implement
+// the corresponding XML-RPC introspection method.
+var ErrIntrospectionUnsupported = errors.New("XML-RPC introspection is not supported")
+
+// ListMethods returns the names of the XML-RPC methods provided by the
+// Aleks service using the standard system.listMethods method.
+func (c *Client) ListMethods(ctx context.Context) ([]string, error) {
+	methods := []string{}
+	if err := c.introspect(ctx, listMethodsMethod, nil, &methods); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// MethodHelp returns the documentation of the named XML-RPC method
+// using the standard system.methodHelp method.
+func (c *Client) MethodHelp(ctx context.Context, method string) (string, error) {
+	help := ""
+	if err := c.introspect(ctx, methodHelpMethod, method, &help); err != nil {
+		return "", err
+	}
+	return help, nil
+}
+
+// MethodSignature returns the signatures of the named XML-RPC method
+// using the standard system.methodSignature method.  Each signature
+// lists the XML-RPC type of the method's result followed by the types
+// of its parameters.  No signatures are returned if the Aleks service
+// doesn't describe the method's signature.
+func (c *Client) MethodSignature(ctx context.Context, method string) ([][]string, error) {
+	var sigs interface{}
+	if err := c.introspect(ctx, methodSignatureMethod, method, &sigs); err != nil {
+		return nil, err
+	}
+	// A non-array value (typically "undef") is returned when a method's
+	// signature isn't known
+	list, ok := sigs.([]interface{})
+	if !ok {
+		return [][]string{}, nil
+	}
+	res := [][]string{}
+	for _, sig := range list {
+		types, ok := sig.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s returned a signature that isn't an array: %v", methodSignatureMethod, sig)
+		}
+		s := []string{}
+		for _, typ := range types {
+			name, ok := typ.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s returned a type that isn't a string: %v", methodSignatureMethod, typ)
+			}
+			s = append(s, name)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// introspect calls the named introspection method, which doesn't
+// require credentials, and reports a method-not-found fault as
+// ErrIntrospectionUnsupported.
+func (c *Client) introspect(ctx context.Context, method string, param interface{}, result interface{}) error {
+	_, err := c.call(ctx, method, param, result)
+	if errors.Is(err, ErrMethodNotFound) {
+		return fmt.Errorf("%w: %s: %v", ErrIntrospectionUnsupported, method, err)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodSignature(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Value    string
+		Expected [][]string
+		Valid    bool
+	}{
+		{"Signatures", `<array><data><value><array><data><value>string</value><value>struct</value></data></array></value></data></array>`, [][]string{{"string", "struct"}}, true},
+		{"Undefined", `<string>undef</string>`, [][]string{}, true},
+		{"Invalid signature", `<array><data><value>string</value></data></array>`, nil, false},
+		{"Invalid type", `<array><data><value><array><data><value><int>1</int></value></data></array></value></data></array>`, nil, false},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+				assert.Equal(t, methodSignatureMethod, mc.MethodName)
+				fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><params><param><value>%s</value></param></params></methodResponse>`, test.Value)
+			})
+			defer srv.Close()
+
+			c, err := NewClient(srv.URL, "username", "password")
+			require.NoError(t, err)
+			sigs, err := c.MethodSignature(context.Background(), "getPlacementReport")
+			if !test.Valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, sigs)
+		})
+	}
+}
+
+func TestIntrospectionUnsupported(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		writeTestFault(w, -32601, "Method not found: "+mc.MethodName)
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	_, err = c.ListMethods(context.Background())
+	assert.True(t, errors.Is(err, ErrIntrospectionUnsupported), err)
+	assert.Equal(t, "XML-RPC introspection is not supported: system.listMethods: Aleks fault -32601: Method not found: system.listMethods", err.Error())
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+const (
+	multicallMethod = "system.multicall"
+)
+
+// multicallCall describes one of the calls batched by system.multicall.
+type multicallCall struct {
+	MethodName string        `xmlrpc:"methodName"`
+	Params     []interface{} `xmlrpc:"params"`
+}
+
+// multicall makes the provided calls in a single system.multicall call,
+// subject to the Client's rate limit and RetryPolicy, and decodes the
+// result of each call into the corresponding element of results (which
+// must be pointers).  Each of the calls counts towards the rate limit.
+// The returned slice contains the fault (or nil) of
+// each call while the returned error describes the failure of the
+// system.multicall call itself.
+func (c *Client) multicall(ctx context.Context, calls []multicallCall, results []interface{}) ([]error, int, error) {
+	raw := []interface{}{}
+	attempts, err := c.callN(ctx, multicallMethod, calls, &raw, len(calls))
+	if err != nil {
+		return nil, attempts, err
+	}
+	if len(raw) != len(calls) {
+		return nil, attempts, fmt.Errorf("%s returned %d results for %d calls", multicallMethod, len(raw), len(calls))
+	}
+	errs := make([]error, len(calls))
+	for idx, r := range raw {
+		switch v := r.(type) {
+		case []interface{}:
+			if len(v) != 1 {
+				return nil, attempts, fmt.Errorf("%s result %d contains %d values", multicallMethod, idx, len(v))
+			}
+			errs[idx] = assignValue(reflect.ValueOf(results[idx]).Elem(), v[0])
+		case map[string]interface{}:
+			errs[idx] = newAleksFault(v)
+		default:
+			return nil, attempts, fmt.Errorf("%s result %d is neither an array nor a fault", multicallMethod, idx)
+		}
+	}
+	return errs, attempts, nil
+}
+
+// pageBatchSize returns the number of placement report pages requested
+// by each call.
+func (c *Client) pageBatchSize() int {
+	if c.multicallSize < 2 || atomic.LoadInt32(&c.multicallRejected) != 0 {
+		return 1
+	}
+	return c.multicallSize
+}
+
+// multicallFallback reports whether the provided system.multicall error
+// shows that the method isn't supported, in which case the Client stops
+// using it.
+func (c *Client) multicallFallback(err error) bool {
+	if !errors.Is(err, ErrMethodNotFound) {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&c.multicallRejected, 0, 1) {
+		c.logger.WithError(err).Warn("system.multicall was rejected, falling back to individual calls")
+	}
+	return true
+}
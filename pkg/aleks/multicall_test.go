@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMulticall(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Result string
+		Valid  bool
+		Errors []error
+	}{
+		{
+			"Values and faults",
+			`<value><array><data><value><string>one</string></value></data></array></value>` +
+				`<value><struct><member><name>faultCode</name><value><int>2</int></value></member><member><name>faultString</name><value><string>Invalid class code</string></value></member></struct></value>`,
+			true,
+			[]error{nil, &AleksFault{Code: 2, Message: "Invalid class code"}},
+		},
+		{
+			"Too few results",
+			`<value><array><data><value><string>one</string></value></data></array></value>`,
+			false,
+			nil,
+		},
+		{
+			"Invalid result",
+			`<value><string>one</string></value><value><string>two</string></value>`,
+			false,
+			nil,
+		},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+				assert.Equal(t, multicallMethod, mc.MethodName)
+				fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>%s</data></array></value></param></params></methodResponse>`, test.Result)
+			})
+			defer srv.Close()
+
+			c, err := NewClient(srv.URL, "username", "password")
+			require.NoError(t, err)
+
+			calls := []multicallCall{{MethodName: "a"}, {MethodName: "b"}}
+			data := make([]string, 2)
+			errs, attempts, err := c.multicall(context.Background(), calls, []interface{}{&data[0], &data[1]})
+			assert.Equal(t, 1, attempts)
+			if !test.Valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.Errors, errs)
+			assert.Equal(t, "one", data[0])
+		})
+	}
+}
+
+func TestMulticallFallback(t *testing.T) {
+	calls := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		calls++
+		if mc.MethodName == multicallMethod {
+			writeTestFault(w, -32601, "Method not found")
+			return
+		}
+		writeTestResponse(w, placementReportEndMarker)
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithMulticall(4))
+	require.NoError(t, err)
+	assert.Equal(t, 4, c.pageBatchSize())
+
+	_, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, errs, 0)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1, c.pageBatchSize())
+}
+
+func TestMulticallRateLimit(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		fmt.Fprint(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`)
+		for i := 0; i < 4; i++ {
+			fmt.Fprint(w, `<value><array><data><value><string>one</string></value></data></array></value>`)
+		}
+		fmt.Fprint(w, `</data></array></value></param></params></methodResponse>`)
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithRateLimit(100, 1))
+	require.NoError(t, err)
+
+	// Each of the batched calls consumes a token
+	calls := make([]multicallCall, 4)
+	data := make([]string, 4)
+	results := []interface{}{&data[0], &data[1], &data[2], &data[3]}
+	_, _, err = c.multicall(context.Background(), calls, results)
+	require.NoError(t, err)
+	c.limiter.mu.Lock()
+	assert.Equal(t, float64(-3), c.limiter.tokens)
+	c.limiter.mu.Unlock()
+}
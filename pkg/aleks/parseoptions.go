@@ -0,0 +1,202 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseMode determines what happens to a PlacementRecord that contains
+// a value that can't be parsed.
+type ParseMode int
+
+const (
+	// ParseLenient keeps the record, with the invalid field left empty
+	// and its column listed in the record's InvalidFields, and reports
+	// an error.  This is the default.
+	ParseLenient ParseMode = iota
+
+	// ParseStrict rejects the record and reports an error.
+	ParseStrict
+
+	// ParseDrop discards the record and reports a single error, whose
+	// cause is ErrRecordDropped, in place of the record's parse errors
+	// so that the loss of the record is visible.
+	ParseDrop
+)
+
+var parseModeNames = []string{"lenient", "strict", "drop"}
+
+// String returns the name of the ParseMode.
+func (m ParseMode) String() string {
+	if m < 0 || int(m) >= len(parseModeNames) {
+		return fmt.Sprintf("ParseMode(%d)", int(m))
+	}
+	return parseModeNames[m]
+}
+
+// UnmarshalText sets the ParseMode from its name (lenient, strict or
+// drop) allowing it to be read from environment variables.
+func (m *ParseMode) UnmarshalText(text []byte) error {
+	for idx, name := range parseModeNames {
+		if strings.EqualFold(string(text), name) {
+			*m = ParseMode(idx)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown parse mode: %s", text)
+}
+
+// ParseOptions determines how the values in a placement report are
+// parsed.  The Mode applies to every column unless overridden by an
+// entry in Columns, which is keyed by column title (e.g. "Placement
+// Results %" - matched ignoring case, and which must be one of the
+// titles in the Aleks report).  The modes of the date and time columns
+// that are combined into a single field can be overridden by either
+// title.  When a record contains invalid values in columns with
+// different modes, ParseStrict takes precedence over ParseDrop which
+// takes precedence over ParseLenient.  The zero value parses every
+// column leniently.
+//
+// The Aleks service reports dates and times in its local time zone, so
+// they're interpreted in the provided Location, which defaults to UTC
+// if nil.  Local times that are ambiguous, or don't exist, due to a
+// daylight saving time transition are described by the records'
+// Warnings.
+type ParseOptions struct {
+	Mode     ParseMode
+	Columns  map[string]ParseMode
+	Location *time.Location
+}
+
+// ParsePlacementReport behaves like the function of the same name but
+// parses the report as described by the ParseOptions.  If the
+// ParseOptions are invalid, a single error is returned without parsing
+// the report.
+func (o ParseOptions) ParsePlacementReport(r io.Reader) (PlacementReport, []error) {
+	if err := o.validate(); err != nil {
+		return PlacementReport{}, []error{err}
+	}
+	return o.parse(r, RecordError{})
+}
+
+func (o ParseOptions) validate() error {
+	if o.Mode < ParseLenient || o.Mode > ParseDrop {
+		return fmt.Errorf("invalid parse mode: %s", o.Mode)
+	}
+	known := map[string]bool{}
+	for _, hdr := range expectedHeaders() {
+		known[columnKey(hdr)] = true
+	}
+	keys := map[string]bool{}
+	for col, mode := range o.Columns {
+		if mode < ParseLenient || mode > ParseDrop {
+			return fmt.Errorf("invalid parse mode for column %q: %s", col, mode)
+		}
+		for _, title := range strings.Split(col, "/") {
+			if !known[columnKey(title)] {
+				return fmt.Errorf("unknown column in parse options: %q", col)
+			}
+		}
+		if keys[columnKey(col)] {
+			return fmt.Errorf("duplicate column in parse options: %q", col)
+		}
+		keys[columnKey(col)] = true
+	}
+	return nil
+}
+
+// location returns the Location in which dates and times are parsed.
+func (o ParseOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+// mode returns the ParseMode of the provided column, which may be a
+// combination of titles separated by a slash.  Titles are matched
+// ignoring case, as they are in the report's header row.
+func (o ParseOptions) mode(column string) ParseMode {
+	if m, ok := o.columnMode(column); ok {
+		return m
+	}
+	for _, title := range strings.Split(column, "/") {
+		if m, ok := o.columnMode(title); ok {
+			return m
+		}
+	}
+	return o.Mode
+}
+
+// columnMode returns the ParseMode in Columns for the provided column
+// title, if any.
+func (o ParseOptions) columnMode(title string) (ParseMode, bool) {
+	for col, m := range o.Columns {
+		if columnKey(col) == columnKey(title) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// apply applies the ParseOptions to a record and the errors that
+// occurred while parsing it and reports whether the record should be
+// kept.  The provided RecordError describes the record.
+func (o ParseOptions) apply(rec PlacementRecord, errs []error, at RecordError) (PlacementRecord, []error, bool) {
+	if len(errs) == 0 {
+		return rec, errs, true
+	}
+	strict, drop := false, false
+	for _, err := range errs {
+		switch o.mode(errorColumn(err)) {
+		case ParseStrict:
+			strict = true
+		case ParseDrop:
+			drop = true
+		}
+	}
+	titles := []string{}
+	for _, err := range errs {
+		// The date and time columns that are combined into a single
+		// field are listed individually
+		titles = append(titles, strings.Split(errorColumn(err), "/")...)
+	}
+	switch {
+	case strict:
+		return rec, errs, false
+	case drop:
+		err := fmt.Errorf("%w: invalid %s", ErrRecordDropped, strings.Join(titles, ", "))
+		return rec, at.append(nil, err), false
+	}
+	rec.InvalidFields = titles
+	return rec, errs, true
+}
+
+// errorColumn returns the column described by the provided error (if
+// it's a RecordError).
+func errorColumn(err error) string {
+	var re *RecordError
+	if errors.As(err, &re) {
+		return re.Column
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:lll
+const testParseOptionsReport = `"Name","Student Id","Email","Last login","Placement Assessment Number","Total Number of Placements Taken","Start Date","Start Time","End Date","End Time","Proctored Assessment","Time in Placement (in hours)","Placement Results %"
+"Doe, John","912345678","JQD5678@PSU.EDU","03/06/2016","1","1","03/06/2016","01:42 PM","03/06/2016","03:23 PM","No/Complete","1.7","62%"
+"Doe, Jane","923456789","JXD6789@PSU.EDU","03/03/2016","1","2","03/03/2016","07:19 PM","03/03/2016","09:30 PM","No/Complete","2.2","??%"
+"Doe, Jim","934567890","JYD7890@PSU.EDU","03/04/2016","1","1","03/04/2016","7:19","03/04/2016","09:30 PM","No/Complete","2.2","75%"`
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Options  ParseOptions
+		Students []string
+		Errors   int
+	}{
+		{"Lenient", ParseOptions{}, []string{"912345678", "923456789", "934567890"}, 2},
+		{"Strict", ParseOptions{Mode: ParseStrict}, []string{"912345678"}, 2},
+		{"Drop", ParseOptions{Mode: ParseDrop}, []string{"912345678"}, 2},
+		{"Strict results", ParseOptions{Columns: map[string]ParseMode{"Placement Results %": ParseStrict}}, []string{"912345678", "934567890"}, 2},
+		{"Strict results ignoring case", ParseOptions{Columns: map[string]ParseMode{"placement results %": ParseStrict}}, []string{"912345678", "934567890"}, 2},
+		{"Unknown column", ParseOptions{Columns: map[string]ParseMode{"Placement Result %": ParseStrict}}, []string{}, 1},
+		{"Drop start time", ParseOptions{Mode: ParseStrict, Columns: map[string]ParseMode{"Start Time": ParseDrop}}, []string{"912345678"}, 2},
+		{"Drop combined start", ParseOptions{Columns: map[string]ParseMode{"Start Date/Start Time": ParseDrop}}, []string{"912345678", "923456789"}, 2},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			pr, errs := test.Options.ParsePlacementReport(strings.NewReader(testParseOptionsReport))
+			assert.Len(t, errs, test.Errors)
+			students := []string{}
+			for _, rec := range pr {
+				students = append(students, rec.StudentID)
+			}
+			assert.Equal(t, test.Students, students)
+		})
+	}
+}
+
+func TestParseOptionsInvalidFields(t *testing.T) {
+	pr, errs := ParsePlacementReport(strings.NewReader(testParseOptionsReport))
+	require.Len(t, pr, 3)
+	require.Len(t, errs, 2)
+	assert.Nil(t, pr[0].InvalidFields)
+	assert.Equal(t, []string{placementReportHeaderColumn12}, pr[1].InvalidFields)
+	assert.Equal(t, float64(0), pr[1].PlacementResults)
+	assert.Equal(t, []string{placementReportHeaderColumn06, placementReportHeaderColumn07}, pr[2].InvalidFields)
+	assert.True(t, pr[2].StartTime.IsZero())
+}
+
+func TestParseOptionsDropErrors(t *testing.T) {
+	pr, errs := ParseOptions{Mode: ParseDrop}.ParsePlacementReport(strings.NewReader(testParseOptionsReport))
+	require.Len(t, pr, 1)
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.True(t, errors.Is(err, ErrRecordDropped), err)
+	}
+	var re *RecordError
+	require.True(t, errors.As(errs[0], &re))
+	assert.Equal(t, 3, re.Line)
+	assert.Equal(t, "923456789", re.StudentID)
+	assert.Equal(t, "line 3, student 923456789: record dropped: invalid Placement Results %", re.Error())
+}
+
+func TestParseModeText(t *testing.T) {
+	var m ParseMode
+	require.NoError(t, m.UnmarshalText([]byte("Strict")))
+	assert.Equal(t, ParseStrict, m)
+	assert.Equal(t, "strict", m.String())
+	assert.Error(t, m.UnmarshalText([]byte("sloppy")))
+	assert.Equal(t, "ParseMode(7)", ParseMode(7).String())
+}
+
+func TestParseOptionsLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	//nolint:lll
+	data := `"Name","Student Id","Last login","Start Date","Start Time","End Date","End Time"
+"Before spring DST","900000001","03/12/2016","03/12/2016","11:00 PM","03/13/2016","01:30 AM"
+"Across spring DST","900000002","03/13/2016","03/13/2016","01:00 AM","03/13/2016","03:30 AM"
+"Across fall DST","900000003","11/06/2016","11/05/2016","11:00 PM","11/06/2016","03:00 AM"
+"After fall DST","900000004","11/07/2016","11/06/2016","02:30 AM","11/06/2016","11:59 PM"`
+	tests := []struct {
+		Name      string
+		LastLogin time.Time
+		Start     time.Time
+		End       time.Time
+		Duration  time.Duration
+	}{
+		{"Before spring DST", time.Date(2016, time.March, 12, 5, 0, 0, 0, time.UTC), time.Date(2016, time.March, 13, 4, 0, 0, 0, time.UTC), time.Date(2016, time.March, 13, 6, 30, 0, 0, time.UTC), 150 * time.Minute},
+		{"Across spring DST", time.Date(2016, time.March, 13, 5, 0, 0, 0, time.UTC), time.Date(2016, time.March, 13, 6, 0, 0, 0, time.UTC), time.Date(2016, time.March, 13, 7, 30, 0, 0, time.UTC), 90 * time.Minute},
+		{"Across fall DST", time.Date(2016, time.November, 6, 4, 0, 0, 0, time.UTC), time.Date(2016, time.November, 6, 3, 0, 0, 0, time.UTC), time.Date(2016, time.November, 6, 8, 0, 0, 0, time.UTC), 5 * time.Hour},
+		{"After fall DST", time.Date(2016, time.November, 7, 5, 0, 0, 0, time.UTC), time.Date(2016, time.November, 6, 7, 30, 0, 0, time.UTC), time.Date(2016, time.November, 7, 4, 59, 0, 0, time.UTC), 21*time.Hour + 29*time.Minute},
+	}
+
+	pr, errs := ParseOptions{Location: ny}.ParsePlacementReport(strings.NewReader(data))
+	require.Len(t, errs, 0)
+	require.Len(t, pr, len(tests))
+	for idx := range tests {
+		test := tests[idx]
+		rec := pr[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Name, rec.Name)
+			assert.True(t, test.LastLogin.Equal(rec.LastLogin), rec.LastLogin.String())
+			assert.True(t, test.Start.Equal(rec.StartTime), rec.StartTime.String())
+			assert.True(t, test.End.Equal(rec.EndTime), rec.EndTime.String())
+			assert.Equal(t, test.Duration, rec.EndTime.Sub(rec.StartTime))
+			assert.Equal(t, ny, rec.StartTime.Location())
+			assert.Empty(t, rec.Warnings)
+		})
+	}
+
+	// The same values are interpreted as UTC by default
+	pr, errs = ParsePlacementReport(strings.NewReader(data))
+	require.Len(t, errs, 0)
+	assert.Equal(t, time.Date(2016, time.March, 12, 23, 0, 0, 0, time.UTC), pr[0].StartTime)
+}
+
+func TestParseOptionsLocalTimeWarnings(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	//nolint:lll
+	data := `"Name","Student Id","Start Date","Start Time","End Date","End Time"
+"Ambiguous","900000001","11/06/2016","01:30 AM","11/06/2016","02:30 AM"
+"Nonexistent","900000002","03/13/2016","01:30 AM","03/13/2016","02:30 AM"`
+	pr, errs := ParseOptions{Location: ny}.ParsePlacementReport(strings.NewReader(data))
+	require.Len(t, errs, 0)
+	require.Len(t, pr, 2)
+
+	// 1:30 AM occurs twice when daylight saving time ends
+	require.Len(t, pr[0].Warnings, 1)
+	var re *RecordError
+	require.True(t, errors.As(pr[0].Warnings[0], &re))
+	assert.Equal(t, placementReportHeaderColumn06+"/"+placementReportHeaderColumn07, re.Column)
+	assert.Equal(t, "11/06/2016 01:30 AM", re.Value)
+	assert.Contains(t, re.Error(), "ambiguous local time in America/New_York")
+
+	// 2:30 AM is skipped when daylight saving time starts
+	require.Len(t, pr[1].Warnings, 1)
+	require.True(t, errors.As(pr[1].Warnings[0], &re))
+	assert.Equal(t, placementReportHeaderColumn08+"/"+placementReportHeaderColumn09, re.Column)
+	assert.Equal(t, "03/13/2016 02:30 AM", re.Value)
+	assert.Contains(t, re.Error(), "nonexistent local time in America/New_York")
+
+	// Every time is unambiguous in UTC
+	pr, errs = ParsePlacementReport(strings.NewReader(data))
+	require.Len(t, errs, 0)
+	for _, rec := range pr {
+		assert.Empty(t, rec.Warnings)
+	}
+}
+
+func TestWithLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// The location is retained by later parse options without one
+	c, err := NewClient("", "username", "password", WithLocation(ny), WithParseOptions(ParseOptions{Mode: ParseStrict}))
+	require.NoError(t, err)
+	assert.Equal(t, ny, c.parseOptions.Location)
+	assert.Equal(t, ParseStrict, c.parseOptions.Mode)
+
+	_, err = NewClient("", "username", "password", WithLocation(nil))
+	assert.Error(t, err)
+}
@@ -16,9 +16,9 @@ limitations under the License.
 
 /*
 Package aleks provides a client library to access data provided by
-the McGraw-Hill Aleks service.  Currently, two methods to retrieve Aleks
+the McGraw-Hill Aleks service.  Currently, several methods to retrieve Aleks
 placement report records are provided by an instantiated client.  See
-the sample program in the cmd/placementreport package for an example of
-how this library can be used.
+the report subcommand of the cmd/aleks program for an example of how
+this library can be used.
 */
 package aleks
@@ -17,6 +17,9 @@ limitations under the License.
 package aleks
 
 import (
+	"bufio"
+	"context"
+	"crypto/sha256"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -24,10 +27,10 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
-	"github.com/kolo/xmlrpc"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -49,9 +52,11 @@ const (
 	placementReportHeaderColumn11         = "Time in Placement (in hours)"
 	placementReportHeaderColumn12         = "Placement Results %"
 	placementReportEndMarker              = "No records found"
-	placementRecordFieldCount             = 13
+	placementReportTrimSet                = " \t\n"
+	utf8BOM                               = "\xef\xbb\xbf"
 	placementRecordDateFormat             = "01/02/2006"
 	placementRecordTimestampFormat        = "01/02/2006 03:04 PM"
+	placementRecordWarningFormat          = "01/02/2006 03:04 PM MST"
 )
 
 const (
@@ -59,7 +64,8 @@ const (
 )
 
 // PlacementReport contains the PlacementRecords returned (if any) by the
-// GetPlacementReport and GetPlacementReportFromEnv methods.
+// GetPlacementReport, GetPlacementReportContext and
+// GetPlacementReportFromEnv methods.
 type PlacementReport []PlacementRecord
 
 // GetPlacementReport calls the Aleks XML-RPC method of the same name for
@@ -68,57 +74,141 @@ type PlacementReport []PlacementRecord
 // process is also collected and returned to the caller.  Note that it is
 // possible for both PlacementRecords and errors to be returned from the
 // same call as valid PlacementRecords are not discarded due to errors
-// in other records.
+// in other records.  Transient failures are retried, page by page, as
+// described by the Client's RetryPolicy.  The retrieval of a class-code's
+// records stops with a PaginationError if it has more pages than allowed
+// by the WithMaxPages option or if a page is identical to the previous
+// page, as the Aleks service would otherwise be called indefinitely.
 //
 // This method uses an individual thread to retrieve the data for each
 // class-code and collects the results in a single PlacementReport to
 // reduce the time it takes to retrieve large data sets.  Requesting
 // large numbers of class-codes will therefore result in a large number
-// of threads.
+// of threads unless the Client was created with the WithMaxConcurrency
+// option, in which case a pool of at most that many threads is used.
 func (c *Client) GetPlacementReport(from, to string, classcodes ...string) (PlacementReport, []error) {
+	return c.GetPlacementReportContext(context.Background(), from, to, classcodes...)
+}
+
+// GetPlacementReportContext behaves like GetPlacementReport but aborts
+// all in-flight class-code requests when the provided context is done.
+// The PlacementRecords retrieved before the context was cancelled (or
+// its deadline exceeded) are returned along with an error for each
+// aborted class-code that wraps the context's error and can therefore
+// be tested with errors.Is.
+func (c *Client) GetPlacementReportContext(ctx context.Context, from, to string, classcodes ...string) (PlacementReport, []error) {
 	pr := PlacementReport{}
-	errs := []error{}
+	errs := c.WalkPlacementReport(ctx, from, to, func(classcode string, page int, rec PlacementRecord) error {
+		pr = append(pr, rec)
+		return nil
+	}, classcodes...)
+	return pr, errs
+}
 
+// ErrStopWalk can be returned by a PlacementRecordWalkFunc to stop
+// WalkPlacementReport without reporting an error.
+var ErrStopWalk = errors.New("stop walking the placement report")
+
+// PlacementRecordWalkFunc is called by WalkPlacementReport for each
+// PlacementRecord along with the class-code and (one-based) page number
+// from which the record was retrieved.  Returning a non-nil error stops
+// the walk.
+type PlacementRecordWalkFunc func(classcode string, page int, rec PlacementRecord) error
+
+// WalkPlacementReport retrieves the same PlacementRecords as
+// GetPlacementReportContext but, rather than collecting them in memory,
+// passes each record to the provided function as soon as the page that
+// contains it has been parsed.  The function is always called from the
+// calling thread so it doesn't need to be safe for concurrent use, and
+// no further pages are retrieved while it is running.
+//
+// If the Aleks service rejects the Client's credentials, all in-flight
+// class-code requests are cancelled and a single error, which is
+// equivalent to ErrAuthentication, is returned in place of any others.
+//
+// If the function returns an error, all in-flight class-code requests
+// are cancelled and the walk stops.  The returned error is included in
+// the returned errors unless it is ErrStopWalk.  Records that are
+// retrieved concurrently before the walk stops are discarded.
+func (c *Client) WalkPlacementReport(ctx context.Context, from, to string, fn PlacementRecordWalkFunc, classcodes ...string) []error {
+	errs := []error{}
 	errs = append(errs, validateRequestDate(from)...)
 	errs = append(errs, validateRequestDate(to)...)
 	errs = append(errs, validateClasscodes(classcodes)...)
 	if len(errs) > 0 {
-		return pr, errs
+		return errs
 	}
 
-	type result struct {
-		PlacementReport PlacementReport
-		Errors          []error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	codes := make(chan string, len(classcodes))
+	for _, code := range classcodes {
+		codes <- code
 	}
-	r := make(chan result, len(classcodes))
+	close(codes)
 
 	// Scatter
-	for _, code := range classcodes {
-		xc, err := xmlrpc.NewClient(c.url, c.trans)
-		if err != nil {
-			errs = append(errs, err)
-			continue
-		}
-		params := map[string]string{
-			"username":             c.username,
-			"password":             c.password,
-			"from_completion_date": from,
-			"to_completion_date":   to,
-			"class_code":           code,
-		}
+	r := make(chan placementReportPage)
+	wg := sync.WaitGroup{}
+	for w := 0; w < c.workerCount(len(classcodes)); w++ {
+		wg.Add(1)
 		go func() {
-			pr, err := getPlacementReportForClasscode(xc, params)
-			r <- result{pr, err}
+			defer wg.Done()
+			for code := range codes {
+				c.getPlacementReportForClasscode(ctx, from, to, code, func(p placementReportPage) {
+					// Cancel before the worker moves on to another
+					// class-code, which would fail for the same reason
+					if authenticationError(p.Errors) != nil {
+						cancel()
+					}
+					r <- p
+				})
+			}
 		}()
 	}
+	go func() {
+		wg.Wait()
+		close(r)
+	}()
 
 	// Gather
-	for i := 0; i < cap(r); i++ {
-		res := <-r
-		errs = append(errs, res.Errors...)
-		pr = append(pr, res.PlacementReport...)
+	stopped := false
+	for p := range r {
+		if stopped {
+			continue
+		}
+		if err := authenticationError(p.Errors); err != nil {
+			// The worker has already cancelled the other class-codes
+			errs = []error{err}
+			stopped = true
+			continue
+		}
+		errs = append(errs, p.Errors...)
+		for _, rec := range p.PlacementReport {
+			err := fn(p.Classcode, p.Page, rec)
+			if err == nil {
+				continue
+			}
+			if err != ErrStopWalk {
+				errs = append(errs, err)
+			}
+			stopped = true
+			cancel()
+			break
+		}
 	}
-	return pr, errs
+	return errs
+}
+
+// placementReportPage contains the PlacementRecords and errors that
+// resulted from retrieving a single page of a class-code's placement
+// report.
+type placementReportPage struct {
+	Classcode       string
+	Page            int
+	PlacementReport PlacementReport
+	Errors          []error
 }
 
 type placementReportEnvConfig struct {
@@ -145,58 +235,272 @@ func (c *Client) GetPlacementReportFromEnv() (PlacementReport, []error) {
 	return c.GetPlacementReport(cfg.From, cfg.To, cfg.Classcodes...)
 }
 
-func getPlacementReportForClasscode(xc *xmlrpc.Client, params map[string]string) (PlacementReport, []error) {
-	rep := PlacementReport{}
-	errs := []error{}
-	for page := 1; true; page++ {
-		params["page_num"] = strconv.FormatInt(int64(page), 10)
-		data := ""
-		err := xc.Call(placementReportMethod, params, &data)
-		if err != nil {
-			return nil, append(errs, err)
+func (c *Client) getPlacementReportForClasscode(ctx context.Context, from, to, code string, emit func(placementReportPage)) {
+	params := c.withCredentials(map[string]string{
+		"from_completion_date": from,
+		"to_completion_date":   to,
+		"class_code":           code,
+	})
+	var prev [sha256.Size]byte
+	for page := 1; true; {
+		if ctx.Err() != nil {
+			emit(placementReportPage{Classcode: code, Page: page, Errors: []error{newCancellationError(code, page, ctx.Err())}})
+			return
 		}
+		results := c.getPlacementReportWindow(ctx, params, page)
+		if ctx.Err() != nil {
+			emit(placementReportPage{Classcode: code, Page: page, Errors: []error{newCancellationError(code, page, ctx.Err())}})
+			return
+		}
+		for _, res := range results {
+			if res.err != nil {
+				err := fmt.Errorf("placement report for class code %s failed at page %d after %d attempt(s): %w", code, page, res.attempts, res.err)
+				emit(placementReportPage{Classcode: code, Page: page, Errors: []error{err}})
+				return
+			}
+
+			if isPlacementReportEnd(res.data) {
+				return
+			}
+			data := strings.Trim(res.data, placementReportTrimSet)
+			if c.maxPages > 0 && page > c.maxPages {
+				err := fmt.Errorf("%w: limit of %d", ErrTooManyPages, c.maxPages)
+				emit(placementReportPage{Classcode: code, Page: page, Errors: []error{&PaginationError{Classcode: code, Page: page, Err: err}}})
+				return
+			}
+			sum := sha256.Sum256([]byte(data))
+			if page > 1 && sum == prev {
+				emit(placementReportPage{Classcode: code, Page: page, Errors: []error{&PaginationError{Classcode: code, Page: page, Err: ErrRepeatedPage}}})
+				return
+			}
+			prev = sum
+			logger := c.logger.WithFields(log.Fields{"class_code": code, "page": page})
+			logger.Debug("Page data: ", data)
 
-		data = strings.Trim(data, " 	\n")
-		if data == placementReportEndMarker {
+			r, e := getPlacementRecordsForPage(code, page, data, c.parseOptions)
+			for _, rec := range r {
+				logger.Debug("Placement record: ", rec)
+				for _, w := range rec.Warnings {
+					logger.Warn(w)
+				}
+			}
+			emit(placementReportPage{Classcode: code, Page: page, PlacementReport: r, Errors: e})
+			page++
+		}
+	}
+}
+
+// getPlacementReportWindow requests the pages starting with the
+// provided page.  Unless the Client was created with the
+// WithPageConcurrency option, a single batch of pages is requested.
+// Otherwise, that many consecutive batches are requested concurrently
+// and the requests for the batches that follow the last page are
+// cancelled.  The results are returned in page order and end with the
+// first batch that didn't return every page it requested.
+func (c *Client) getPlacementReportWindow(ctx context.Context, params map[string]string, first int) []placementReportResult {
+	n := c.pageBatchSize()
+	if c.pageWindow < 2 {
+		return c.getPlacementReportPages(ctx, params, first, n)
+	}
+
+	batches := make([][]placementReportResult, c.pageWindow)
+	cancels := make([]context.CancelFunc, c.pageWindow)
+	ctxs := make([]context.Context, c.pageWindow)
+	for idx := range ctxs {
+		ctxs[idx], cancels[idx] = context.WithCancel(ctx)
+		defer cancels[idx]()
+	}
+	wg := sync.WaitGroup{}
+	for idx := range batches {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			batches[idx] = c.getPlacementReportPages(ctxs[idx], params, first+idx*n, n)
+			if endsWindow(batches[idx], n) {
+				// The batches that follow will be discarded
+				for _, cancel := range cancels[idx+1:] {
+					cancel()
+				}
+			}
+		}(idx)
+	}
+	wg.Wait()
+
+	results := []placementReportResult{}
+	for _, batch := range batches {
+		results = append(results, batch...)
+		if endsWindow(batch, n) {
 			break
 		}
-		log.Debug("Page data: ", data)
+	}
+	return results
+}
 
-		r, e := getPlacementRecordsForPage(data)
-		rep = append(rep, r...)
-		errs = append(errs, e...)
+// endsWindow reports whether the pages that follow the provided batch
+// of results, for which n pages were requested, will be discarded
+// because the batch contains the last page or a failure or because it
+// didn't return every page.
+func endsWindow(batch []placementReportResult, n int) bool {
+	if len(batch) < n {
+		return true
 	}
-	return rep, errs
+	for _, res := range batch {
+		if res.err != nil || isPlacementReportEnd(res.data) {
+			return true
+		}
+	}
+	return false
 }
 
-func getPlacementRecordsForPage(data string) (PlacementReport, []error) {
-	rdr := csv.NewReader(strings.NewReader(data))
-	rdr.FieldsPerRecord = placementRecordFieldCount
+// placementReportResult is the outcome of requesting a single page of
+// a class-code's placement report.
+type placementReportResult struct {
+	data     string
+	attempts int
+	err      error
+}
+
+// getPlacementReportPages requests n consecutive pages, starting with
+// the provided page, in a single system.multicall call if n is greater
+// than one.  If the call fails, a single result describing the failure
+// of the first page is returned.
+func (c *Client) getPlacementReportPages(ctx context.Context, params map[string]string, first, n int) []placementReportResult {
+	if n > 1 {
+		calls := make([]multicallCall, n)
+		data := make([]string, n)
+		results := make([]interface{}, n)
+		for idx := range calls {
+			calls[idx] = multicallCall{
+				MethodName: placementReportMethod,
+				Params:     []interface{}{pageParams(params, first+idx)},
+			}
+			results[idx] = &data[idx]
+		}
+		errs, attempts, err := c.multicall(ctx, calls, results)
+		if err == nil {
+			res := make([]placementReportResult, n)
+			for idx := range res {
+				res[idx] = placementReportResult{data: data[idx], attempts: attempts, err: errs[idx]}
+			}
+			return res
+		}
+		if !c.multicallFallback(err) {
+			return []placementReportResult{{attempts: attempts, err: err}}
+		}
+	}
+	res := placementReportResult{}
+	res.attempts, res.err = c.call(ctx, placementReportMethod, pageParams(params, first), &res.data)
+	return []placementReportResult{res}
+}
+
+// isPlacementReportEnd reports whether the provided page data is the
+// end marker that follows the last page of a placement report.
+func isPlacementReportEnd(data string) bool {
+	return strings.Trim(data, placementReportTrimSet) == placementReportEndMarker
+}
+
+// pageParams returns a copy of the provided getPlacementReport
+// parameters that requests the provided page.
+func pageParams(params map[string]string, page int) map[string]string {
+	p := make(map[string]string, len(params)+1)
+	for k, v := range params {
+		p[k] = v
+	}
+	p["page_num"] = strconv.FormatInt(int64(page), 10)
+	return p
+}
+
+// ParsePlacementReport parses a CSV formatted placement report, such as
+// one downloaded from the Aleks web interface, in the same manner as the
+// pages retrieved by GetPlacementReport.  The first CSV record must be
+// the header row.  As with GetPlacementReport, both PlacementRecords and
+// errors (typically RecordErrors) can be returned.  The values are
+// parsed leniently - see ParseOptions for alternatives.
+func ParsePlacementReport(r io.Reader) (PlacementReport, []error) {
+	return ParseOptions{}.ParsePlacementReport(r)
+}
+
+func getPlacementRecordsForPage(classcode string, page int, data string, opts ParseOptions) (PlacementReport, []error) {
+	return opts.parse(strings.NewReader(data), RecordError{Classcode: classcode, Page: page})
+}
+
+// parse parses the CSV formatted placement records read from the
+// provided reader.  The provided RecordError describes the source of
+// the records in the errors that are returned.
+func (o ParseOptions) parse(in io.Reader, src RecordError) (PlacementReport, []error) {
+	// Skip the UTF-8 byte order mark (if any) written by spreadsheet
+	// applications
+	br := bufio.NewReader(in)
+	if bom, err := br.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+	// Every record must have as many columns as the header row
+	rdr := csv.NewReader(br)
+	rdr.FieldsPerRecord = 0
 	rdr.ReuseRecord = true
 
 	rep := PlacementReport{}
 	errs := []error{}
-	for first := true; true; first = false {
+	cols := placementReportColumns{}
+	for line := 1; true; line++ {
+		at := src
+		at.Line = line
 		rec, err := rdr.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			errs = append(errs, err)
+			errs = at.append(errs, err)
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				// The reader failed rather than the CSV record
+				break
+			}
 			continue
 		}
-		if first {
-			errs = append(errs, validateHeaders(rec)...)
+		if line == 1 {
+			var e []error
+			var ok bool
+			cols, e, ok = newPlacementReportColumns(rec, at)
+			errs = append(errs, e...)
+			if !ok {
+				// Records that can't be attributed to a student are
+				// useless so the whole page fails
+				return rep, errs
+			}
 			continue
 		}
-		r, e := newPlacementRecord(rec)
-		log.Debug("Placement record: ", r)
+		at.StudentID, _ = cols.value(rec, placementReportHeaderColumn01)
+		r, e := newPlacementRecord(rec, cols, o.location(), at)
+		r, e, keep := o.apply(r, e, at)
 		errs = append(errs, e...)
-		rep = append(rep, r)
+		if keep {
+			rep = append(rep, r)
+		}
 	}
 	return rep, errs
 }
 
+// authenticationError returns an error describing the first of the
+// provided errors that is equivalent to ErrAuthentication (if any).
+func authenticationError(errs []error) error {
+	for _, err := range errs {
+		if !errors.Is(err, ErrAuthentication) {
+			continue
+		}
+		var fault *AleksFault
+		if errors.As(err, &fault) {
+			err = fault
+		}
+		return fmt.Errorf("placement report aborted: %w", err)
+	}
+	return nil
+}
+
+func newCancellationError(classcode string, page int, err error) error {
+	return fmt.Errorf("placement report for class code %s aborted before page %d completed: %w", classcode, page, err)
+}
+
 func validateClasscodes(classcodes []string) []error {
 	errs := []error{}
 	re, err := regexp.Compile(placementReportRequestClasscodeFormat)
@@ -211,18 +515,6 @@ func validateClasscodes(classcodes []string) []error {
 	return errs
 }
 
-func validateHeaders(record []string) []error {
-	errs := []error{}
-	for idx, hdr := range record {
-		exp := expectedHeaders()[idx]
-		if hdr != exp {
-			msg := fmt.Sprintf("Unexpected header column title (%d) - expected: %s, actual: %s", idx, hdr, exp)
-			errs = append(errs, errors.New(msg))
-		}
-	}
-	return errs
-}
-
 func validateRequestDate(value string) []error {
 	errs := []error{}
 	_, err := time.Parse(placementReportRequestDateFormat, value)
@@ -250,13 +542,98 @@ func expectedHeaders() []string {
 	}
 }
 
+// requiredHeaders returns the titles of the columns that identify the
+// student to whom each record belongs and must therefore be present.
+func requiredHeaders() []string {
+	return []string{
+		placementReportHeaderColumn00,
+		placementReportHeaderColumn01,
+	}
+}
+
+// placementReportColumns maps the column titles in the header row of a
+// placement report to their positions so that the columns can appear
+// in any order.  Titles are matched ignoring case and surrounding
+// spaces.  The positions of unknown columns are retained so that their
+// values can be returned in each PlacementRecord's Extra map.
+type placementReportColumns struct {
+	index  map[string]int
+	extra  []int
+	titles []string
+}
+
+// newPlacementReportColumns returns the placementReportColumns described
+// by the provided header row along with errors describing duplicate
+// and missing required columns, and whether the required columns are
+// present.
+func newPlacementReportColumns(header []string, at RecordError) (placementReportColumns, []error, bool) {
+	cols := placementReportColumns{
+		index:  map[string]int{},
+		titles: make([]string, len(header)),
+	}
+	known := map[string]bool{}
+	for _, hdr := range expectedHeaders() {
+		known[columnKey(hdr)] = true
+	}
+	errs := []error{}
+	for idx, hdr := range header {
+		hdr = strings.TrimSpace(hdr)
+		cols.titles[idx] = hdr
+		key := columnKey(hdr)
+		if prev, ok := cols.index[key]; ok {
+			msg := fmt.Sprintf("Duplicate header column title (%d) - first used by column %d", idx, prev)
+			errs = at.field(hdr, hdr).append(errs, errors.New(msg))
+			continue
+		}
+		cols.index[key] = idx
+		if !known[key] {
+			cols.extra = append(cols.extra, idx)
+		}
+	}
+	ok := true
+	for _, hdr := range requiredHeaders() {
+		if _, found := cols.index[columnKey(hdr)]; !found {
+			errs = at.field(hdr, "").append(errs, errors.New("Missing required header column title"))
+			ok = false
+		}
+	}
+	return cols, errs, ok
+}
+
+// columnKey returns the key by which the column with the provided title
+// is indexed.
+func columnKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// value returns the value of the column with the provided title and
+// whether the column is present.
+func (cols placementReportColumns) value(rec []string, title string) (string, bool) {
+	idx, ok := cols.index[columnKey(title)]
+	if !ok || idx >= len(rec) {
+		return "", false
+	}
+	return rec[idx], true
+}
+
 // PlacementRecord provides the results of an individual placement exam.
 // The Aleks format returns 13 strings formatted as a CSV record.  The
-// 11 fields below are in the same order and (almost) have the same names
-// as the columns in this CSV report - the "Start Date"/"Start Time" and
-// "End Date"/"End Time" columns are combined into a single field below.
-// In addition, all string columns are validated and converted to their
-// appropriate types.
+// 11 fields below (almost) have the same names as the columns in this
+// CSV report - the "Start Date"/"Start Time" and "End Date"/"End Time"
+// columns are combined into a single field below.  In addition, all
+// string columns are validated and converted to their appropriate
+// types.  The columns are identified by the titles in the report's
+// header row, ignoring case, rather than their order, and only the
+// "Name" and "Student Id" columns are required - the fields of missing
+// columns are left empty while a page without either of the required
+// columns fails without any records.  The values of any columns with
+// unknown titles are stored in the Extra map (which is nil if there are
+// none) by title.  The titles of the columns whose values couldn't be
+// parsed, and whose fields were therefore left empty, are listed in
+// InvalidFields.  Values that were parsed but not fully recognized,
+// such as an unknown ProctoredAssessment status, are described by
+// Warnings (which are *RecordErrors) rather than being reported as
+// errors.
 type PlacementRecord struct {
 	Name                         string
 	StudentID                    string
@@ -266,65 +643,136 @@ type PlacementRecord struct {
 	TotalNumberOfPlacementsTaken int
 	StartTime                    time.Time
 	EndTime                      time.Time
-	ProctoredAssessment          string
+	ProctoredAssessment          ProctoredAssessment
 	HoursInPlacement             float64
 	PlacementResults             float64
+	Extra                        map[string]string
+	InvalidFields                []string
+	Warnings                     []error
 }
 
-func newPlacementRecord(rec []string) (PlacementRecord, []error) {
-	log.Debug(" CSV record: ", rec)
+func newPlacementRecord(rec []string, cols placementReportColumns, loc *time.Location, at RecordError) (PlacementRecord, []error) {
 	errs := []error{}
-	lastLogin, errs := parseDate(rec[3], errs)
-	placementAssessmentNumber, errs := parseInt(rec[4], errs)
-	totalNumberOfPlacementsTaken, errs := parseInt(rec[5], errs)
-	startTime, errs := parseTime(rec[6]+" "+rec[7], errs)
-	endTime, errs := parseTime(rec[8]+" "+rec[9], errs)
-	hoursInPlacement, errs := parseFloat(rec[11], errs)
-	placementResults, errs := parseFloat(rec[12], errs)
-	return PlacementRecord{
-		Name:                         rec[0],
-		StudentID:                    rec[1],
-		Email:                        rec[2],
-		LastLogin:                    lastLogin,
-		PlacementAssessmentNumber:    placementAssessmentNumber,
-		TotalNumberOfPlacementsTaken: totalNumberOfPlacementsTaken,
-		StartTime:                    startTime,
-		EndTime:                      endTime,
-		ProctoredAssessment:          rec[10],
-		HoursInPlacement:             hoursInPlacement,
-		PlacementResults:             placementResults,
-	}, errs
-}
-
-func parseDate(value string, errs []error) (time.Time, []error) {
-	d, err := time.Parse(placementRecordDateFormat, value)
+	r := PlacementRecord{}
+	r.Name, _ = cols.value(rec, placementReportHeaderColumn00)
+	r.StudentID, _ = cols.value(rec, placementReportHeaderColumn01)
+	r.Email, _ = cols.value(rec, placementReportHeaderColumn02)
+	if v, ok := cols.value(rec, placementReportHeaderColumn03); ok {
+		f := at.field(placementReportHeaderColumn03, v)
+		r.LastLogin, errs = parseDate(f, loc, errs)
+		r.Warnings = checkLocalTime(f, placementRecordDateFormat, loc, r.Warnings)
+	}
+	if v, ok := cols.value(rec, placementReportHeaderColumn04); ok {
+		r.PlacementAssessmentNumber, errs = parseInt(at.field(placementReportHeaderColumn04, v), errs)
+	}
+	if v, ok := cols.value(rec, placementReportHeaderColumn05); ok {
+		r.TotalNumberOfPlacementsTaken, errs = parseInt(at.field(placementReportHeaderColumn05, v), errs)
+	}
+	if d, t, ok := cols.timestamp(rec, placementReportHeaderColumn06, placementReportHeaderColumn07); ok {
+		f := at.field(placementReportHeaderColumn06+"/"+placementReportHeaderColumn07, d+" "+t)
+		r.StartTime, errs = parseTime(f, loc, errs)
+		r.Warnings = checkLocalTime(f, placementRecordTimestampFormat, loc, r.Warnings)
+	}
+	if d, t, ok := cols.timestamp(rec, placementReportHeaderColumn08, placementReportHeaderColumn09); ok {
+		f := at.field(placementReportHeaderColumn08+"/"+placementReportHeaderColumn09, d+" "+t)
+		r.EndTime, errs = parseTime(f, loc, errs)
+		r.Warnings = checkLocalTime(f, placementRecordTimestampFormat, loc, r.Warnings)
+	}
+	if v, ok := cols.value(rec, placementReportHeaderColumn10); ok {
+		r.ProctoredAssessment, r.Warnings = parseProctoredAssessment(at.field(placementReportHeaderColumn10, v), r.Warnings)
+	}
+	if v, ok := cols.value(rec, placementReportHeaderColumn11); ok {
+		r.HoursInPlacement, errs = parseFloat(at.field(placementReportHeaderColumn11, v), errs)
+	}
+	if v, ok := cols.value(rec, placementReportHeaderColumn12); ok {
+		r.PlacementResults, errs = parseFloat(at.field(placementReportHeaderColumn12, v), errs)
+	}
+	for _, idx := range cols.extra {
+		if idx >= len(rec) {
+			continue
+		}
+		if r.Extra == nil {
+			r.Extra = map[string]string{}
+		}
+		r.Extra[cols.titles[idx]] = rec[idx]
+	}
+	return r, errs
+}
+
+// timestamp returns the values of the provided date and time columns
+// and whether both are present.
+func (cols placementReportColumns) timestamp(rec []string, dateTitle, timeTitle string) (string, string, bool) {
+	d, ok := cols.value(rec, dateTitle)
+	if !ok {
+		return "", "", false
+	}
+	t, ok := cols.value(rec, timeTitle)
+	return d, t, ok
+}
+
+func parseDate(f RecordError, loc *time.Location, errs []error) (time.Time, []error) {
+	d, err := time.ParseInLocation(placementRecordDateFormat, f.Value, loc)
 	if err != nil {
-		errs = append(errs, err)
+		errs = f.append(errs, err)
 	}
 	return d, errs
 }
 
-func parseInt(value string, errs []error) (int, []error) {
-	i, err := strconv.ParseInt(value, 10, 64)
+// checkLocalTime appends a warning to the provided warnings if the
+// date or time in the provided field, which is formatted using the
+// provided layout, is ambiguous (i.e. it's repeated when daylight
+// saving time ends) or doesn't exist (i.e. it's skipped when daylight
+// saving time starts) in the provided location.  Values that can't be
+// parsed are ignored as they're reported as errors.
+func checkLocalTime(f RecordError, layout string, loc *time.Location, warns []error) []error {
+	wall, err := time.Parse(layout, f.Value)
 	if err != nil {
-		errs = append(errs, err)
+		return warns
+	}
+	t, _ := time.ParseInLocation(layout, f.Value, loc)
+	// The wall clock time is valid for each of the nearby offsets at
+	// which it identifies an instant with that offset
+	offsets := map[int]bool{}
+	for _, probe := range []time.Time{t.Add(-12 * time.Hour), t, t.Add(12 * time.Hour)} {
+		_, offset := probe.In(loc).Zone()
+		offsets[offset] = true
+	}
+	valid := 0
+	for offset := range offsets {
+		if _, o := wall.Add(-time.Duration(offset) * time.Second).In(loc).Zone(); o == offset {
+			valid++
+		}
+	}
+	switch {
+	case valid > 1:
+		return f.append(warns, fmt.Errorf("ambiguous local time in %s, interpreted as %s", loc, t.Format(placementRecordWarningFormat)))
+	case valid == 0:
+		return f.append(warns, fmt.Errorf("nonexistent local time in %s, interpreted as %s", loc, t.Format(placementRecordWarningFormat)))
+	}
+	return warns
+}
+
+func parseInt(f RecordError, errs []error) (int, []error) {
+	i, err := strconv.ParseInt(f.Value, 10, 64)
+	if err != nil {
+		errs = f.append(errs, err)
 	}
 	return int(i), errs
 }
 
-func parseFloat(value string, errs []error) (float64, []error) {
-	value = strings.ReplaceAll(value, "%", "")
-	f, err := strconv.ParseFloat(value, 64)
+func parseFloat(f RecordError, errs []error) (float64, []error) {
+	value := strings.ReplaceAll(f.Value, "%", "")
+	fl, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		errs = append(errs, err)
+		errs = f.append(errs, err)
 	}
-	return f, errs
+	return fl, errs
 }
 
-func parseTime(value string, errs []error) (time.Time, []error) {
-	t, err := time.Parse(placementRecordTimestampFormat, value)
+func parseTime(f RecordError, loc *time.Location, errs []error) (time.Time, []error) {
+	t, err := time.ParseInLocation(placementRecordTimestampFormat, f.Value, loc)
 	if err != nil {
-		errs = append(errs, err)
+		errs = f.append(errs, err)
 	}
 	return t, errs
 }
@@ -17,6 +17,17 @@ limitations under the License.
 package aleks
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -89,7 +100,7 @@ func TestPlacementReport(t *testing.T) {
 			TotalNumberOfPlacementsTaken: 1,
 			StartTime:                    time.Date(2016, time.March, 6, 13, 42, 0, 0, time.UTC),
 			EndTime:                      time.Date(2016, time.March, 6, 15, 23, 0, 0, time.UTC),
-			ProctoredAssessment:          "No/Complete",
+			ProctoredAssessment:          ProctoredAssessment{Raw: "No/Complete", Proctored: ProctoredNo, Status: AssessmentComplete},
 			HoursInPlacement:             1.7,
 			PlacementResults:             62,
 		},
@@ -102,13 +113,464 @@ func TestPlacementReport(t *testing.T) {
 			TotalNumberOfPlacementsTaken: 2,
 			StartTime:                    time.Date(2016, time.March, 3, 19, 19, 0, 0, time.UTC),
 			EndTime:                      time.Date(2016, time.March, 3, 21, 30, 0, 0, time.UTC),
-			ProctoredAssessment:          "No/Complete",
+			ProctoredAssessment:          ProctoredAssessment{Raw: "No/Complete", Proctored: ProctoredNo, Status: AssessmentComplete},
 			HoursInPlacement:             2.2,
 			PlacementResults:             81,
 		},
 	}
-	pr, errs := getPlacementRecordsForPage(data)
+	pr, errs := getPlacementRecordsForPage("ABCDE-FGHIJ", 1, data, ParseOptions{})
 	require.Len(t, errs, 0)
 	require.Len(t, pr, 2)
 	assert.Equal(t, exp, pr)
 }
+
+//nolint:lll
+const testPlacementReportPage = `"Name","Student Id","Email","Last login","Placement Assessment Number","Total Number of Placements Taken","Start Date","Start Time","End Date","End Time","Proctored Assessment","Time in Placement (in hours)","Placement Results %"
+"Doe, John","912345678","JQD5678@PSU.EDU","03/06/2016","1","1","03/06/2016","01:42 PM","03/06/2016","03:23 PM","No/Complete","1.7","62%"`
+
+// testPlacementReportPageFor returns the testPlacementReportPage with an
+// email address that varies by page so that consecutive pages differ.
+func testPlacementReportPageFor(mc testMethodCall) string {
+	return strings.Replace(testPlacementReportPage, "JQD5678@", "JQD5678+"+mc.param("page_num")+"@", 1)
+}
+
+type testMethodCall struct {
+	MethodName string `xml:"methodName"`
+	Members    []struct {
+		Name  string `xml:"name"`
+		Value string `xml:"value>string"`
+	} `xml:"params>param>value>struct>member"`
+}
+
+func (mc testMethodCall) param(name string) string {
+	for _, m := range mc.Members {
+		if m.Name == name {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// newTestServer returns an XML-RPC server that decodes each call and
+// passes it to the provided handler.
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, mc testMethodCall)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mc := testMethodCall{}
+		if err := xml.NewDecoder(r.Body).Decode(&mc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler(w, r, mc)
+	}))
+}
+
+// writeTestFault writes an XML-RPC fault response.
+func writeTestFault(w http.ResponseWriter, code int, msg string) {
+	fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><fault><value><struct><member><name>faultCode</name><value><int>%d</int></value></member><member><name>faultString</name><value><string>%s</string></value></member></struct></value></fault></methodResponse>`, code, msg)
+}
+
+// writeTestResponse writes a CDATA wrapped XML-RPC string response.
+func writeTestResponse(w http.ResponseWriter, data string) {
+	fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><params><param><value><string><![CDATA[%s]]></string></value></param></params></methodResponse>`, data)
+}
+
+func TestGetPlacementReportContextCancellation(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		if mc.param("page_num") == "1" {
+			writeTestResponse(w, testPlacementReportPageFor(mc))
+			return
+		}
+		<-r.Context().Done()
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	pr, errs := c.GetPlacementReportContext(ctx, "2016-01-01", "2016-12-31", "ABCDE-FGHIJ", "KLMNO-PQRST")
+	assert.Len(t, pr, 2)
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), err.Error())
+	}
+}
+
+func TestGetPlacementReportMaxConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		if mc.param("page_num") == "1" {
+			writeTestResponse(w, testPlacementReportPageFor(mc))
+			return
+		}
+		writeTestResponse(w, placementReportEndMarker)
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithMaxConcurrency(2))
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "AAAAA-AAAAA", "BBBBB-BBBBB", "CCCCC-CCCCC", "DDDDD-DDDDD", "EEEEE-EEEEE")
+	assert.Len(t, errs, 0)
+	assert.Len(t, pr, 5)
+	assert.LessOrEqual(t, peak, 2)
+}
+
+func TestGetPlacementReportRetry(t *testing.T) {
+	var mu sync.Mutex
+	failures := map[string]int{"2": 1, "3": 5}
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		page := mc.param("page_num")
+		mu.Lock()
+		defer mu.Unlock()
+		if failures[page] > 0 {
+			failures[page]--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writeTestResponse(w, testPlacementReportPageFor(mc))
+	})
+	defer srv.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 1}
+	c, err := NewClient(srv.URL, "username", "password", WithRetryPolicy(policy))
+	require.NoError(t, err)
+
+	// Page 2 succeeds on its second attempt while page 3 exhausts its
+	// attempts - the records from the first two pages are retained.
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, pr, 2)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "page 3 after 3 attempt(s)")
+	assert.Equal(t, 2, failures["3"])
+}
+
+func TestGetPlacementReportPageConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	requested := map[int]bool{}
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		page, err := strconv.Atoi(mc.param("page_num"))
+		require.NoError(t, err)
+		mu.Lock()
+		requested[page] = true
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		// Later pages respond first so that they arrive out of order
+		time.Sleep(time.Duration(10-page%4) * 5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		if page >= 6 {
+			writeTestResponse(w, placementReportEndMarker)
+			return
+		}
+		writeTestResponse(w, testPlacementReportPageFor(mc))
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithPageConcurrency(4))
+	require.NoError(t, err)
+
+	pages := []int{}
+	errs := c.WalkPlacementReport(context.Background(), "2016-01-01", "2016-12-31", func(classcode string, page int, rec PlacementRecord) error {
+		pages = append(pages, page)
+		return nil
+	}, "ABCDE-FGHIJ")
+	assert.Len(t, errs, 0)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, pages)
+	assert.Equal(t, 4, peak)
+	// Pages 1-4 and 5-8 are requested, the overshoot pages are discarded
+	assert.Len(t, requested, 8)
+}
+
+func TestGetPlacementReportPageConcurrencyFailure(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		if mc.param("page_num") == "2" {
+			writeTestFault(w, 99, "Try again later")
+			return
+		}
+		writeTestResponse(w, testPlacementReportPageFor(mc))
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithPageConcurrency(3))
+	require.NoError(t, err)
+
+	// Page 3 is discarded as it follows the failed page
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, pr, 1)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "page 2")
+}
+
+func TestGetPlacementReportMaxPages(t *testing.T) {
+	tests := []struct {
+		Name     string
+		MaxPages int
+		Records  int
+		Aborted  bool
+	}{
+		{"Exceeded", 3, 3, true},
+		{"Last page", 4, 4, false},
+		{"Unlimited", 0, 4, false},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+				if mc.param("page_num") == "5" {
+					writeTestResponse(w, placementReportEndMarker)
+					return
+				}
+				writeTestResponse(w, testPlacementReportPageFor(mc))
+			})
+			defer srv.Close()
+
+			c, err := NewClient(srv.URL, "username", "password", WithMaxPages(test.MaxPages))
+			require.NoError(t, err)
+
+			pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+			assert.Len(t, pr, test.Records)
+			if !test.Aborted {
+				assert.Len(t, errs, 0)
+				return
+			}
+			require.Len(t, errs, 1)
+			var pe *PaginationError
+			require.True(t, errors.As(errs[0], &pe), errs[0].Error())
+			assert.Equal(t, "ABCDE-FGHIJ", pe.Classcode)
+			assert.Equal(t, 4, pe.Page)
+			assert.True(t, errors.Is(errs[0], ErrPaginationAborted))
+			assert.True(t, errors.Is(errs[0], ErrTooManyPages))
+			assert.Equal(t, "placement report for class code ABCDE-FGHIJ pagination aborted at page 4: too many pages: limit of 3", errs[0].Error())
+		})
+	}
+}
+
+func TestGetPlacementReportRepeatedPage(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		writeTestResponse(w, testPlacementReportPage)
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password", WithMaxPages(0))
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ", "KLMNO-PQRST")
+	assert.Len(t, pr, 2)
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		var pe *PaginationError
+		require.True(t, errors.As(err, &pe), err.Error())
+		assert.Equal(t, 2, pe.Page)
+		assert.True(t, errors.Is(err, ErrPaginationAborted))
+		assert.True(t, errors.Is(err, ErrRepeatedPage))
+	}
+}
+
+func TestWalkPlacementReport(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		if mc.param("page_num") == "3" {
+			writeTestResponse(w, placementReportEndMarker)
+			return
+		}
+		writeTestResponse(w, testPlacementReportPageFor(mc))
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	pages := map[string][]int{}
+	errs := c.WalkPlacementReport(context.Background(), "2016-01-01", "2016-12-31", func(classcode string, page int, rec PlacementRecord) error {
+		assert.Equal(t, "912345678", rec.StudentID)
+		pages[classcode] = append(pages[classcode], page)
+		return nil
+	}, "ABCDE-FGHIJ", "KLMNO-PQRST")
+	assert.Len(t, errs, 0)
+	assert.Equal(t, map[string][]int{"ABCDE-FGHIJ": {1, 2}, "KLMNO-PQRST": {1, 2}}, pages)
+}
+
+func TestWalkPlacementReportStop(t *testing.T) {
+	// The end marker is never returned so the walk only ends when the
+	// walk function stops it.
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		writeTestResponse(w, testPlacementReportPageFor(mc))
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	count := 0
+	errs := c.WalkPlacementReport(context.Background(), "2016-01-01", "2016-12-31", func(classcode string, page int, rec PlacementRecord) error {
+		count++
+		if count == 3 {
+			return ErrStopWalk
+		}
+		return nil
+	}, "ABCDE-FGHIJ")
+	assert.Len(t, errs, 0)
+	assert.Equal(t, 3, count)
+
+	stop := errors.New("stop")
+	errs = c.WalkPlacementReport(context.Background(), "2016-01-01", "2016-12-31", func(classcode string, page int, rec PlacementRecord) error {
+		return stop
+	}, "ABCDE-FGHIJ")
+	assert.Equal(t, []error{stop}, errs)
+}
+
+func TestPlacementReportRecordErrors(t *testing.T) {
+	//nolint:lll
+	data := `"Name","Student Id","Email","Last login","Placement Assessment Number","Total Number of Placements Taken","Start Date","Start Time","End Date","End Time","Proctored Assessment","Time in Placement (in hours)","Placement Results %"
+"Doe, John","912345678","JQD5678@PSU.EDU","03/06/2016","1","1","03/06/2016","01:42 PM","03/06/2016","03:23 PM","No/Complete","1.7","62%"
+"Doe, Jane","923456789","JXD6789@PSU.EDU","03/03/2016","one","2","03/03/2016","07:19 PM","03/03/2016","09:30 PM","No/Complete","2.2","81%"`
+	pr, errs := getPlacementRecordsForPage("ABCDE-FGHIJ", 2, data, ParseOptions{})
+	assert.Len(t, pr, 2)
+	require.Len(t, errs, 1)
+
+	var re *RecordError
+	require.True(t, errors.As(errs[0], &re))
+	assert.Equal(t, "ABCDE-FGHIJ", re.Classcode)
+	assert.Equal(t, 2, re.Page)
+	assert.Equal(t, 3, re.Line)
+	assert.Equal(t, "923456789", re.StudentID)
+	assert.Equal(t, placementReportHeaderColumn04, re.Column)
+	assert.Equal(t, "one", re.Value)
+	assert.True(t, errors.Is(errs[0], strconv.ErrSyntax))
+	assert.Equal(t, `class code ABCDE-FGHIJ, page 2, line 3, student 923456789, column "Placement Assessment Number", value "one": strconv.ParseInt: parsing "one": invalid syntax`, errs[0].Error())
+}
+
+func TestParsePlacementReport(t *testing.T) {
+	// A download saved by a spreadsheet application with a byte order
+	// mark and CRLF line endings
+	data := "\xef\xbb\xbf" + strings.ReplaceAll(testPlacementReportPage, "\n", "\r\n") + "\r\n"
+	pr, errs := ParsePlacementReport(strings.NewReader(data))
+	assert.Len(t, errs, 0)
+	require.Len(t, pr, 1)
+	assert.Equal(t, "Doe, John", pr[0].Name)
+	assert.Equal(t, "912345678", pr[0].StudentID)
+	assert.Equal(t, time.Date(2016, time.March, 6, 15, 23, 0, 0, time.UTC), pr[0].EndTime)
+
+	pr, errs = ParsePlacementReport(strings.NewReader(testPlacementReportPage + "\n\"Doe, Malformed\",\"900000000\""))
+	assert.Len(t, pr, 1)
+	require.Len(t, errs, 1)
+	var re *RecordError
+	require.True(t, errors.As(errs[0], &re))
+	assert.Equal(t, "", re.Classcode)
+	assert.Equal(t, 0, re.Page)
+	assert.Equal(t, 3, re.Line)
+	assert.True(t, errors.Is(errs[0], csv.ErrFieldCount))
+}
+
+func TestParsePlacementReportReadError(t *testing.T) {
+	pr, errs := ParsePlacementReport(io.MultiReader(strings.NewReader(testPlacementReportPage+"\n"), errReader{io.ErrUnexpectedEOF}))
+	assert.Len(t, pr, 1)
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], io.ErrUnexpectedEOF))
+}
+
+func TestPlacementReportHeaderErrors(t *testing.T) {
+	//nolint:lll
+	data := `"Name","Student ID","Email","Last login","Placement Assessment Number","Total Number of Placements Taken","Start Date","Start Time","End Date","End Time","Proctored Assessment","Time in Placement (in hours)","Placement Results %","Email"
+"Doe, John","912345678","JQD5678@PSU.EDU","03/06/2016","1","1","03/06/2016","01:42 PM","03/06/2016","03:23 PM","No/Complete","1.7","62%","other@psu.edu"`
+	pr, errs := getPlacementRecordsForPage("ABCDE-FGHIJ", 1, data, ParseOptions{})
+	require.Len(t, errs, 1)
+
+	var re *RecordError
+	require.True(t, errors.As(errs[0], &re))
+	assert.Equal(t, 1, re.Line)
+	assert.Equal(t, placementReportHeaderColumn02, re.Column)
+	assert.Contains(t, re.Error(), "Duplicate header column title (13)")
+
+	// Titles are matched ignoring case and the first of the duplicate
+	// columns is used
+	require.Len(t, pr, 1)
+	assert.Equal(t, "912345678", pr[0].StudentID)
+	assert.Equal(t, "JQD5678@PSU.EDU", pr[0].Email)
+	assert.Nil(t, pr[0].Extra)
+
+	// A page without a required column fails, whatever the ParseMode
+	missing := strings.Replace(data, `"Student ID"`, `"Student Number"`, 1)
+	for _, mode := range []ParseMode{ParseLenient, ParseStrict, ParseDrop} {
+		pr, errs = getPlacementRecordsForPage("ABCDE-FGHIJ", 1, missing, ParseOptions{Mode: mode})
+		assert.Len(t, pr, 0, mode.String())
+		require.Len(t, errs, 2, mode.String())
+		require.True(t, errors.As(errs[1], &re))
+		assert.Equal(t, 1, re.Line)
+		assert.Equal(t, placementReportHeaderColumn01, re.Column)
+		assert.Contains(t, re.Error(), "Missing required header column title")
+	}
+}
+
+func TestPlacementReportHeaderCase(t *testing.T) {
+	pr, errs := getPlacementRecordsForPage("ABCDE-FGHIJ", 1, strings.ToUpper(testPlacementReportPage), ParseOptions{})
+	assert.Len(t, errs, 0)
+	require.Len(t, pr, 1)
+	assert.Equal(t, "912345678", pr[0].StudentID)
+	assert.Equal(t, 62.0, pr[0].PlacementResults)
+	assert.Nil(t, pr[0].Extra)
+}
+
+func TestPlacementReportColumnMapping(t *testing.T) {
+	//nolint:lll
+	data := `"Student Id","Placement Results %","Name","Campus","End Date","End Time","Start Date","Last login"," Section "
+"912345678","62%","Doe, John","UP","03/06/2016","03:23 PM","03/06/2016","03/06/2016","001"`
+	pr, errs := getPlacementRecordsForPage("ABCDE-FGHIJ", 1, data, ParseOptions{})
+	assert.Len(t, errs, 0)
+	require.Len(t, pr, 1)
+	assert.Equal(t, PlacementRecord{
+		Name:             "Doe, John",
+		StudentID:        "912345678",
+		LastLogin:        time.Date(2016, time.March, 6, 0, 0, 0, 0, time.UTC),
+		EndTime:          time.Date(2016, time.March, 6, 15, 23, 0, 0, time.UTC),
+		PlacementResults: 62,
+		Extra:            map[string]string{"Campus": "UP", "Section": "001"},
+	}, pr[0])
+
+	// Records must have as many columns as the header row
+	_, errs = getPlacementRecordsForPage("ABCDE-FGHIJ", 1, data+"\n\"923456789\",\"81%\"", ParseOptions{})
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], csv.ErrFieldCount))
+}
+
+func TestGetPlacementReportAuthenticationFailure(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		writeTestFault(w, 1, "Invalid username or password")
+	})
+	defer srv.Close()
+
+	c, err := NewClient(srv.URL, "username", "stale", WithMaxConcurrency(1))
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "AAAAA-AAAAA", "BBBBB-BBBBB", "CCCCC-CCCCC", "DDDDD-DDDDD", "EEEEE-EEEEE")
+	assert.Len(t, pr, 0)
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], ErrAuthentication))
+	assert.Equal(t, "placement report aborted: Aleks fault 1: Invalid username or password", errs[0].Error())
+	mu.Lock()
+	assert.Equal(t, 1, calls)
+	mu.Unlock()
+}
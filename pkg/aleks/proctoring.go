@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Proctored indicates whether a placement assessment was proctored.
+type Proctored int
+
+const (
+	// ProctoredUnknown indicates that the report didn't say whether the
+	// assessment was proctored (or said so in an unrecognized way).
+	ProctoredUnknown Proctored = iota
+
+	// ProctoredNo indicates that the assessment wasn't proctored.
+	ProctoredNo
+
+	// ProctoredYes indicates that the assessment was proctored.
+	ProctoredYes
+)
+
+var proctoredNames = []string{"Unknown", "No", "Yes"}
+
+// String returns the name of the Proctored value as it appears in a
+// placement report.
+func (p Proctored) String() string {
+	return enumName(proctoredNames, int(p), "Proctored")
+}
+
+// MarshalText returns the name of the Proctored value.
+func (p Proctored) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText sets the Proctored value from its (case-insensitive)
+// name.
+func (p *Proctored) UnmarshalText(text []byte) error {
+	idx, ok := enumValue(proctoredNames, string(text))
+	if !ok {
+		return fmt.Errorf("unknown proctored value: %s", text)
+	}
+	*p = Proctored(idx)
+	return nil
+}
+
+// AssessmentStatus is the status of a placement assessment.
+type AssessmentStatus int
+
+const (
+	// AssessmentStatusUnknown indicates that the report didn't include
+	// the assessment's status (or included an unrecognized one).
+	AssessmentStatusUnknown AssessmentStatus = iota
+
+	// AssessmentComplete indicates that the assessment was completed.
+	AssessmentComplete
+
+	// AssessmentInProgress indicates that the assessment was started
+	// but hasn't been completed.
+	AssessmentInProgress
+
+	// AssessmentExpired indicates that the assessment wasn't completed
+	// in the time allowed.
+	AssessmentExpired
+)
+
+var assessmentStatusNames = []string{"Unknown", "Complete", "In Progress", "Expired"}
+
+// String returns the name of the AssessmentStatus as it appears in a
+// placement report.
+func (s AssessmentStatus) String() string {
+	return enumName(assessmentStatusNames, int(s), "AssessmentStatus")
+}
+
+// MarshalText returns the name of the AssessmentStatus.
+func (s AssessmentStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText sets the AssessmentStatus from its name, which is
+// matched ignoring case and spaces (e.g. "in progress" or
+// "InProgress").
+func (s *AssessmentStatus) UnmarshalText(text []byte) error {
+	idx, ok := enumValue(assessmentStatusNames, string(text))
+	if !ok {
+		return fmt.Errorf("unknown assessment status: %s", text)
+	}
+	*s = AssessmentStatus(idx)
+	return nil
+}
+
+// ProctoredAssessment is the value of the "Proctored Assessment" column
+// of a placement report, such as "No/Complete", which combines whether
+// the assessment was proctored with its status.  The value is retained
+// in Raw, exactly as it was reported, in case the parts aren't
+// recognized.
+type ProctoredAssessment struct {
+	Raw       string
+	Proctored Proctored
+	Status    AssessmentStatus
+}
+
+// String returns the Raw value or, if it's empty, the value that the
+// report would contain for the Proctored value and Status.
+func (pa ProctoredAssessment) String() string {
+	if pa.Raw != "" || (pa.Proctored == ProctoredUnknown && pa.Status == AssessmentStatusUnknown) {
+		return pa.Raw
+	}
+	return pa.Proctored.String() + "/" + pa.Status.String()
+}
+
+// parseProctoredAssessment parses the provided "Proctored Assessment"
+// value.  Unrecognized parts are left unknown and described by the
+// returned warnings, rather than errors, as the remainder of the record
+// is still usable.  An empty value is unknown without a warning.
+func parseProctoredAssessment(f RecordError, warns []error) (ProctoredAssessment, []error) {
+	pa := ProctoredAssessment{Raw: f.Value}
+	if strings.TrimSpace(f.Value) == "" {
+		return pa, warns
+	}
+	parts := strings.SplitN(f.Value, "/", 2)
+	if len(parts) != 2 {
+		return pa, f.append(warns, fmt.Errorf("expected proctored/status"))
+	}
+	if err := pa.Proctored.UnmarshalText([]byte(parts[0])); err != nil || pa.Proctored == ProctoredUnknown {
+		pa.Proctored = ProctoredUnknown
+		warns = f.append(warns, fmt.Errorf("unknown proctored value: %s", parts[0]))
+	}
+	if err := pa.Status.UnmarshalText([]byte(parts[1])); err != nil || pa.Status == AssessmentStatusUnknown {
+		pa.Status = AssessmentStatusUnknown
+		warns = f.append(warns, fmt.Errorf("unknown assessment status: %s", parts[1]))
+	}
+	return pa, warns
+}
+
+// enumName returns the name of the provided value of an enumeration or
+// a description of the value if it's out of range.
+func enumName(names []string, value int, typ string) string {
+	if value < 0 || value >= len(names) {
+		return fmt.Sprintf("%s(%d)", typ, value)
+	}
+	return names[value]
+}
+
+// enumValue returns the index of the provided name, which is matched
+// ignoring case and spaces.
+func enumValue(names []string, name string) (int, bool) {
+	name = strings.ReplaceAll(name, " ", "")
+	for idx, n := range names {
+		if strings.EqualFold(name, strings.ReplaceAll(n, " ", "")) {
+			return idx, true
+		}
+	}
+	return 0, false
+}
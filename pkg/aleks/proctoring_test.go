@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProctoredAssessment(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Value     string
+		Proctored Proctored
+		Status    AssessmentStatus
+		Warnings  int
+	}{
+		{"Not proctored", "No/Complete", ProctoredNo, AssessmentComplete, 0},
+		{"Proctored", "Yes/Complete", ProctoredYes, AssessmentComplete, 0},
+		{"In progress", "Yes/In Progress", ProctoredYes, AssessmentInProgress, 0},
+		{"In progress without space", "no/inprogress", ProctoredNo, AssessmentInProgress, 0},
+		{"Expired", "No/Expired", ProctoredNo, AssessmentExpired, 0},
+		{"Empty", "", ProctoredUnknown, AssessmentStatusUnknown, 0},
+		{"Unknown proctored", "Maybe/Complete", ProctoredUnknown, AssessmentComplete, 1},
+		{"Unknown status", "No/Abandoned", ProctoredNo, AssessmentStatusUnknown, 1},
+		{"Literal unknown", "Unknown/Unknown", ProctoredUnknown, AssessmentStatusUnknown, 2},
+		{"No separator", "Complete", ProctoredUnknown, AssessmentStatusUnknown, 1},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			at := RecordError{Line: 2, StudentID: "912345678"}
+			pa, warns := parseProctoredAssessment(at.field(placementReportHeaderColumn10, test.Value), nil)
+			assert.Equal(t, ProctoredAssessment{Raw: test.Value, Proctored: test.Proctored, Status: test.Status}, pa)
+			assert.Len(t, warns, test.Warnings)
+			for _, w := range warns {
+				var re *RecordError
+				require.True(t, errors.As(w, &re))
+				assert.Equal(t, 2, re.Line)
+				assert.Equal(t, "912345678", re.StudentID)
+				assert.Equal(t, placementReportHeaderColumn10, re.Column)
+				assert.Equal(t, test.Value, re.Value)
+			}
+		})
+	}
+}
+
+func TestProctoredAssessmentWarnings(t *testing.T) {
+	data := strings.Replace(testParseOptionsReport, "No/Complete", "Maybe/Complete", 1)
+	pr, errs := ParseOptions{Mode: ParseStrict}.ParsePlacementReport(strings.NewReader(data))
+	assert.Len(t, errs, 2)
+	require.Len(t, pr, 1)
+	assert.Equal(t, "Maybe/Complete", pr[0].ProctoredAssessment.Raw)
+	assert.Equal(t, AssessmentComplete, pr[0].ProctoredAssessment.Status)
+	assert.Empty(t, pr[0].InvalidFields)
+	require.Len(t, pr[0].Warnings, 1)
+	assert.Contains(t, pr[0].Warnings[0].Error(), "unknown proctored value: Maybe")
+
+	// Warnings are encoded as their messages
+	b, err := json.Marshal(pr[0].Warnings)
+	require.NoError(t, err)
+	assert.Equal(t, `["line 2, student 912345678, column \"Proctored Assessment\", value \"Maybe/Complete\": unknown proctored value: Maybe"]`, string(b))
+}
+
+func TestProctoredAssessmentString(t *testing.T) {
+	assert.Equal(t, "Yes/In Progress", ProctoredAssessment{Proctored: ProctoredYes, Status: AssessmentInProgress}.String())
+	assert.Equal(t, "yes/in progress", ProctoredAssessment{Raw: "yes/in progress", Proctored: ProctoredYes, Status: AssessmentInProgress}.String())
+	assert.Equal(t, "", ProctoredAssessment{}.String())
+	assert.Equal(t, "Proctored(7)", Proctored(7).String())
+	assert.Equal(t, "AssessmentStatus(-1)", AssessmentStatus(-1).String())
+}
+
+func TestProctoredAssessmentJSON(t *testing.T) {
+	in := ProctoredAssessment{Raw: "No/Expired", Proctored: ProctoredNo, Status: AssessmentExpired}
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Raw":"No/Expired","Proctored":"No","Status":"Expired"}`, string(b))
+
+	out := ProctoredAssessment{}
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+	assert.Error(t, json.Unmarshal([]byte(`{"Status":"Abandoned"}`), &out))
+}
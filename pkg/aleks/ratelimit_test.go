@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	// The first two tokens are available immediately and the remaining
+	// four are each refilled in 10ms.
+	assert.True(t, time.Since(start) >= 35*time.Millisecond, time.Since(start).String())
+}
+
+func TestRateLimiterN(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	start := time.Now()
+	// Taking five tokens leaves a debt of three, which takes 30ms to
+	// repay, and the next token takes a further 10ms.
+	require.NoError(t, l.waitN(context.Background(), 5))
+	assert.True(t, time.Since(start) >= 25*time.Millisecond, time.Since(start).String())
+	start = time.Now()
+	require.NoError(t, l.wait(context.Background()))
+	assert.True(t, time.Since(start) >= 5*time.Millisecond, time.Since(start).String())
+}
+
+func TestRateLimiterCancellation(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	require.NoError(t, l.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.wait(ctx))
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	var l *rateLimiter
+	assert.NoError(t, l.wait(context.Background()))
+}
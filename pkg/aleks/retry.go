@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how failed XML-RPC calls that are likely to
+// succeed if repeated (timeouts, connection resets, connections closed
+// before a response and HTTP 500, 502, 503 and 504 responses) are
+// retried.  The delay before the second attempt is
+// InitialBackoff and each subsequent delay is multiplied by Multiplier
+// up to a maximum of MaxBackoff.  Each delay is then randomly adjusted
+// by up to plus or minus Jitter (a fraction between 0 and 1) of its
+// value so that concurrent retries are spread out over time.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+}
+
+// DefaultRetryPolicy is used by clients that aren't created with the
+// WithRetryPolicy option.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 1 {
+		return errors.New("retry policy must allow at least one attempt")
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return errors.New("retry policy backoff durations must not be negative")
+	}
+	if p.Multiplier < 1 {
+		return errors.New("retry policy multiplier must be at least one")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("retry policy jitter must be between zero and one")
+	}
+	return nil
+}
+
+// backoff returns the delay that precedes the provided (one-based)
+// attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-2))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	d += d * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec
+	return time.Duration(d)
+}
+
+// do calls fn until it succeeds, returns an error that isn't retryable,
+// the maximum number of attempts is reached or the context is done.
+// The number of attempts made is returned with the last error.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return attempt - 1, ctx.Err()
+			case <-time.After(p.backoff(attempt)):
+			}
+		}
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !isRetryable(err) {
+			return attempt, err
+		}
+	}
+}
+
+// isRetryable reports whether the provided error is transient and the
+// call that produced it is therefore likely to succeed if repeated.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// An attempt's own deadline (see WithTimeout) has passed - do stops
+	// retrying when the caller's context is done
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	// The HTTP client only reports io.EOF (as a url.Error) when the
+	// connection is closed before any of the response is received
+	var urlErr *url.Error
+	if errors.Is(err, io.EOF) && errors.As(err, &urlErr) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
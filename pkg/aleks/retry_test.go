@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Err       error
+		Retryable bool
+	}{
+		{"Timeout", &url.Error{Op: "Post", URL: "https://example.com", Err: timeoutError{}}, true},
+		{"Connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"Service unavailable", &StatusError{StatusCode: 503, Status: "503 Service Unavailable"}, true},
+		{"Not found", &StatusError{StatusCode: 404, Status: "404 Not Found"}, false},
+		{"Not implemented", &StatusError{StatusCode: 501, Status: "501 Not Implemented"}, false},
+		{"HTTP version not supported", &StatusError{StatusCode: 505, Status: "505 HTTP Version Not Supported"}, false},
+		{"Closed before response", &url.Error{Op: "Post", URL: "https://example.com", Err: io.EOF}, true},
+		{"EOF reading response", fmt.Errorf("reading response: %w", io.EOF), false},
+		{"Truncated response", fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), true},
+		{"Fault", &AleksFault{Code: 2, Message: "Invalid class code"}, false},
+		{"Cancelled", fmt.Errorf("wrapped: %w", context.Canceled), false},
+		{"Attempt deadline", &url.Error{Op: "Post", URL: "https://example.com", Err: context.DeadlineExceeded}, true},
+		{"Other", errors.New("something else"), false},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, isRetryable(test.Err))
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(t, time.Second, p.backoff(2))
+	assert.Equal(t, 2*time.Second, p.backoff(3))
+	assert.Equal(t, 3*time.Second, p.backoff(4))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		assert.True(t, d >= 500*time.Millisecond && d <= 1500*time.Millisecond, d.String())
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, Multiplier: 1}
+	transient := &StatusError{StatusCode: 500, Status: "500 Internal Server Error"}
+
+	calls := 0
+	attempts, err := p.do(context.Background(), func() error {
+		calls++
+		return transient
+	})
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, transient, err)
+
+	calls = 0
+	attempts, err = p.do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return transient
+		}
+		return nil
+	})
+	assert.Equal(t, 2, attempts)
+	assert.NoError(t, err)
+
+	calls = 0
+	attempts, err = p.do(context.Background(), func() error {
+		calls++
+		return errors.New("permanent")
+	})
+	assert.Equal(t, 1, attempts)
+	assert.Error(t, err)
+}
+
+func TestRetryPolicyAttemptTimeout(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request, mc testMethodCall) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		writeTestResponse(w, "ok")
+	})
+	defer srv.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, Multiplier: 1}
+	c, err := NewClient(srv.URL, "username", "password", WithTimeout(50*time.Millisecond), WithRetryPolicy(policy))
+	require.NoError(t, err)
+
+	// The first attempt times out and is retried
+	res := ""
+	require.NoError(t, c.CallIdempotent(context.Background(), "getOtherReport", nil, &res))
+	assert.Equal(t, "ok", res)
+	mu.Lock()
+	assert.Equal(t, 2, calls)
+	mu.Unlock()
+
+	// The caller's deadline isn't retried
+	mu.Lock()
+	calls = 0
+	mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = c.CallIdempotent(ctx, "getOtherReport", nil, &res)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	mu.Lock()
+	assert.Equal(t, 1, calls)
+	mu.Unlock()
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// WithTLSConfig uses a copy of the provided TLS configuration for the
+// connections made to the Aleks service.  The other TLS options alter
+// this configuration and, when they precede this option (e.g. when set
+// by NewClientFromEnv), their settings are merged into the copy: their
+// client certificates are added, the higher minimum version is used,
+// both certificate verification callbacks must succeed and their root
+// certificate authorities are used unless the provided configuration
+// also has some, which is an error.  The Client's TLS configuration is
+// merged into that of the transport (see WithTransport) in the same
+// way.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) error {
+		if cfg == nil {
+			return errors.New("TLS configuration must not be nil")
+		}
+		merged, err := mergeTLSConfig(cfg, c.tlsConfig)
+		if err != nil {
+			return err
+		}
+		c.tlsConfig = merged
+		return nil
+	}
+}
+
+// mergeTLSConfig returns a copy of the base configuration into which
+// the settings made by the TLS options in the provided configuration
+// (which may be nil) have been merged.
+func mergeTLSConfig(base, opts *tls.Config) (*tls.Config, error) {
+	merged := base.Clone()
+	if opts == nil {
+		return merged, nil
+	}
+	if opts.RootCAs != nil {
+		if merged.RootCAs != nil {
+			return nil, errors.New("TLS configuration's root certificate authorities conflict with those of the TLS options")
+		}
+		merged.RootCAs = opts.RootCAs
+	}
+	merged.Certificates = append(merged.Certificates, opts.Certificates...)
+	if opts.MinVersion > merged.MinVersion {
+		merged.MinVersion = opts.MinVersion
+	}
+	merged.VerifyPeerCertificate = chainVerifyPeerCertificate(merged.VerifyPeerCertificate, opts.VerifyPeerCertificate)
+	return merged, nil
+}
+
+// WithRootCAs replaces the system's certificate authorities with the
+// provided pool when verifying the Aleks service's certificate.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Client) error {
+		if pool == nil {
+			return errors.New("root certificate authority pool must not be nil")
+		}
+		c.tls().RootCAs = pool
+		return nil
+	}
+}
+
+// WithCAFile adds the PEM encoded certificate authorities in the named
+// file to those trusted when verifying the Aleks service's certificate.
+// This is generally required when connecting through an inspecting
+// proxy with a private certificate authority.
+func WithCAFile(name string) Option {
+	return func(c *Client) error {
+		pem, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		cfg := c.tls()
+		if cfg.RootCAs == nil {
+			cfg.RootCAs, err = x509.SystemCertPool()
+			if err != nil {
+				cfg.RootCAs = x509.NewCertPool()
+			}
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file: %s", name)
+		}
+		return nil
+	}
+}
+
+// WithClientCertificate presents the provided certificate when the
+// server requests one (i.e. for mutual TLS).
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *Client) error {
+		cfg := c.tls()
+		cfg.Certificates = append(cfg.Certificates, cert)
+		return nil
+	}
+}
+
+// WithClientCertificateFiles behaves like WithClientCertificate but
+// loads the PEM encoded certificate and key from the named files.
+func WithClientCertificateFiles(certFile, keyFile string) Option {
+	return func(c *Client) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		return WithClientCertificate(cert)(c)
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version (e.g. tls.VersionTLS12)
+// that is acceptable.
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *Client) error {
+		if version < tls.VersionTLS10 || version > tls.VersionTLS13 {
+			return fmt.Errorf("unsupported TLS version: %#04x", version)
+		}
+		c.tls().MinVersion = version
+		return nil
+	}
+}
+
+// WithPinnedSHA256 only allows connections to servers whose verified
+// certificate chain contains a certificate whose public key (i.e. its
+// DER encoded SubjectPublicKeyInfo) has one of the provided SHA-256
+// fingerprints.  Fingerprints may be hex (optionally colon separated)
+// or base64 encoded.  Pinning is in addition to the normal certificate
+// verification, which must therefore not be disabled, and to any
+// VerifyPeerCertificate callback of a WithTLSConfig configuration.
+func WithPinnedSHA256(fingerprints ...string) Option {
+	return func(c *Client) error {
+		if len(fingerprints) == 0 {
+			return errors.New("at least one pinned fingerprint is required")
+		}
+		pins := map[[sha256.Size]byte]bool{}
+		for _, fp := range fingerprints {
+			pin, err := parseFingerprint(fp)
+			if err != nil {
+				return err
+			}
+			pins[pin] = true
+		}
+		// The certificates sent by the server are ignored as they may
+		// include certificates that aren't part of any verified chain
+		verify := func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			for _, chain := range verifiedChains {
+				for _, cert := range chain {
+					if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+						return nil
+					}
+				}
+			}
+			return errors.New("no verified certificate presented by the server matches a pinned fingerprint")
+		}
+		cfg := c.tls()
+		cfg.VerifyPeerCertificate = chainVerifyPeerCertificate(cfg.VerifyPeerCertificate, verify)
+		return nil
+	}
+}
+
+// chainVerifyPeerCertificate returns a VerifyPeerCertificate callback
+// that requires both of the provided callbacks, either of which may be
+// nil, to succeed.
+func chainVerifyPeerCertificate(first, second func([][]byte, [][]*x509.Certificate) error) func([][]byte, [][]*x509.Certificate) error {
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if err := first(rawCerts, verifiedChains); err != nil {
+			return err
+		}
+		return second(rawCerts, verifiedChains)
+	}
+}
+
+func parseFingerprint(fp string) ([sha256.Size]byte, error) {
+	pin := [sha256.Size]byte{}
+	b, err := hex.DecodeString(strings.ReplaceAll(fp, ":", ""))
+	if err != nil {
+		b, err = base64.StdEncoding.DecodeString(fp)
+	}
+	if err != nil || len(b) != sha256.Size {
+		return pin, fmt.Errorf("invalid SHA-256 fingerprint: %s", fp)
+	}
+	copy(pin[:], b)
+	return pin, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version: %s", version)
+}
+
+// tls returns the Client's TLS configuration, creating it if necessary.
+func (c *Client) tls() *tls.Config {
+	if c.tlsConfig == nil {
+		c.tlsConfig = &tls.Config{} //nolint:gosec
+	}
+	return c.tlsConfig
+}
+
+// applyTLSConfig returns a copy of the provided transport that uses the
+// Client's TLS configuration merged into the transport's own.
+func (c *Client) applyTLSConfig(base http.RoundTripper) (http.RoundTripper, error) {
+	if c.tlsConfig == nil {
+		return base, nil
+	}
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return nil, errors.New("TLS options require the transport to be an *http.Transport")
+	}
+	cfg := c.tlsConfig
+	if t.TLSClientConfig != nil {
+		var err error
+		cfg, err = mergeTLSConfig(t.TLSClientConfig, c.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	t = t.Clone()
+	t.TLSClientConfig = cfg
+	return t, nil
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTLSTestServer(t *testing.T, clientAuth tls.ClientAuthType) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestResponse(w, "ok")
+	}))
+	srv.TLS = &tls.Config{ClientAuth: clientAuth} //nolint:gosec
+	srv.StartTLS()
+	return srv
+}
+
+func callTLSTestServer(t *testing.T, srv *httptest.Server, opts ...Option) error {
+	t.Helper()
+	opts = append(opts, WithRetryPolicy(RetryPolicy{MaxAttempts: 1, Multiplier: 1}))
+	c, err := NewClient(srv.URL, "username", "password", opts...)
+	require.NoError(t, err)
+	res := ""
+	return c.Call(context.Background(), "getPlacementReport", nil, &res)
+}
+
+func TestRootCAs(t *testing.T) {
+	srv := newTLSTestServer(t, tls.NoClientCert)
+	defer srv.Close()
+
+	assert.Error(t, callTLSTestServer(t, srv))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	assert.NoError(t, callTLSTestServer(t, srv, WithRootCAs(pool)))
+
+	f, err := ioutil.TempFile("", "aleks-ca-*.pem")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	require.NoError(t, f.Close())
+	assert.NoError(t, callTLSTestServer(t, srv, WithCAFile(f.Name())))
+}
+
+func TestPinnedSHA256(t *testing.T) {
+	srv := newTLSTestServer(t, tls.NoClientCert)
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	assert.NoError(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithPinnedSHA256(hex.EncodeToString(sum[:]))))
+
+	other := sha256.Sum256([]byte("other"))
+	assert.Error(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithPinnedSHA256(hex.EncodeToString(other[:]))))
+
+	_, err := NewClient("", "username", "password", WithPinnedSHA256("not-a-fingerprint"))
+	assert.Error(t, err)
+}
+
+func TestPinnedSHA256AppendedCertificate(t *testing.T) {
+	ca, caKey := newTestCertificate(t, nil, nil, true)
+	leaf, leafKey := newTestCertificate(t, ca, caKey, false)
+	pinned, _ := newTestCertificate(t, nil, nil, false)
+
+	// The server's chain is valid but the pinned certificate, which
+	// follows it, isn't part of the verified chain
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestResponse(w, "ok")
+	}))
+	srv.TLS = &tls.Config{ //nolint:gosec
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.Raw, pinned.Raw}, PrivateKey: leafKey}},
+	}
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	sum := sha256.Sum256(pinned.RawSubjectPublicKeyInfo)
+	assert.Error(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithPinnedSHA256(hex.EncodeToString(sum[:]))))
+
+	sum = sha256.Sum256(ca.RawSubjectPublicKeyInfo)
+	assert.NoError(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithPinnedSHA256(hex.EncodeToString(sum[:]))))
+}
+
+func TestWithTLSConfigMerge(t *testing.T) {
+	srv := newTLSTestServer(t, tls.NoClientCert)
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	pin := hex.EncodeToString(sum[:])
+	other := sha256.Sum256([]byte("other"))
+
+	// Earlier options are merged into the configuration
+	assert.NoError(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithPinnedSHA256(pin), WithTLSConfig(&tls.Config{})))                        //nolint:gosec
+	assert.Error(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithPinnedSHA256(hex.EncodeToString(other[:])), WithTLSConfig(&tls.Config{}))) //nolint:gosec
+
+	// The configuration's callback is kept by later options
+	called := false
+	cfg := &tls.Config{ //nolint:gosec
+		VerifyPeerCertificate: func([][]byte, [][]*x509.Certificate) error {
+			called = true
+			return nil
+		},
+	}
+	assert.NoError(t, callTLSTestServer(t, srv, WithTLSConfig(cfg), WithRootCAs(pool), WithPinnedSHA256(pin)))
+	assert.True(t, called)
+
+	c, err := NewClient("", "username", "password", WithMinTLSVersion(tls.VersionTLS13), WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})) //nolint:gosec
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), c.tlsConfig.MinVersion)
+
+	_, err = NewClient("", "username", "password", WithRootCAs(pool), WithTLSConfig(&tls.Config{RootCAs: pool})) //nolint:gosec
+	assert.Error(t, err)
+}
+
+func TestTLSOptionsMergeTransport(t *testing.T) {
+	srv := newTLSTestServer(t, tls.NoClientCert)
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+
+	// The transport's root certificate authorities are kept
+	trans := http.DefaultTransport.(*http.Transport).Clone()
+	trans.TLSClientConfig = &tls.Config{RootCAs: pool, ServerName: "example.com"} //nolint:gosec
+	c, err := NewClient(srv.URL, "username", "password", WithTransport(trans), WithMinTLSVersion(tls.VersionTLS12))
+	require.NoError(t, err)
+	cfg := c.hc.Transport.(*http.Transport).TLSClientConfig
+	assert.Equal(t, pool, cfg.RootCAs)
+	assert.Equal(t, "example.com", cfg.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Nil(t, trans.TLSClientConfig.VerifyPeerCertificate)
+
+	trans.TLSClientConfig.ServerName = ""
+	assert.NoError(t, callTLSTestServer(t, srv, WithTransport(trans), WithPinnedSHA256(hex.EncodeToString(sum[:]))))
+
+	_, err = NewClient(srv.URL, "username", "password", WithTransport(trans), WithRootCAs(pool))
+	assert.Error(t, err)
+}
+
+// newTestCertificate returns a certificate for 127.0.0.1 (or a
+// certificate authority) signed by the provided parent or, if nil,
+// self-signed.
+func newTestCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestClientCertificate(t *testing.T) {
+	srv := newTLSTestServer(t, tls.RequireAnyClientCert)
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	assert.Error(t, callTLSTestServer(t, srv, WithRootCAs(pool)))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	assert.NoError(t, callTLSTestServer(t, srv, WithRootCAs(pool), WithClientCertificate(cert)))
+}
+
+func TestTLSOptionsValidation(t *testing.T) {
+	_, err := NewClient("", "username", "password", WithTransport(&countingRoundTripper{}), WithMinTLSVersion(tls.VersionTLS12))
+	assert.Error(t, err)
+
+	_, err = NewClient("", "username", "password", WithMinTLSVersion(0))
+	assert.Error(t, err)
+
+	c, err := NewClient("", "username", "password", WithTLSConfig(&tls.Config{ServerName: "example.com"}), WithMinTLSVersion(tls.VersionTLS12)) //nolint:gosec
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", c.tlsConfig.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), c.tlsConfig.MinVersion)
+}
@@ -17,13 +17,29 @@ limitations under the License.
 package aleks
 
 import (
-	"io/ioutil"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
-	"strings"
 	"time"
 )
 
+const (
+	cdataStart = "<![CDATA["
+	cdataEnd   = "]]>"
+
+	// cdataChunkSize is the number of bytes read from a response body
+	// at a time.
+	cdataChunkSize = 4096
+)
+
+// ErrResponseTooLarge is returned when a response body exceeds the
+// maximum size configured via the WithMaxResponseSize option or the
+// RoundTripper's MaxResponseSize field.
+var ErrResponseTooLarge = errors.New("response body exceeds the maximum size")
+
 // aleksTransport is equivalent to the http.DefaultTransport with
 // compression disabled.  See https://golang.org/pkg/net/http/#RoundTripper.
 func transport() *http.Transport {
@@ -44,23 +60,40 @@ func transport() *http.Transport {
 }
 
 // RoundTripper intercepts HTTP calls and alters the request as described
-// by the #RoundTrip method.
+// by the #RoundTrip method.  The AleksDefaultUserAgent is sent if the
+// UserAgent is empty.  Response bodies larger than MaxResponseSize bytes
+// (if non-zero) result in an error that wraps ErrResponseTooLarge.
+//
+// Deprecated: the Client decodes responses with its own XML-RPC codec,
+// which handles CDATA sections, and no longer uses a RoundTripper.  It
+// is retained for callers that use it with other XML-RPC libraries.
 type RoundTripper struct {
-	Trans http.RoundTripper
+	Trans           http.RoundTripper
+	UserAgent       string
+	MaxResponseSize int64
 }
 
 // RoundTrip implements https://golang.org/pkg/net/http/#RoundTripper.
 // The XML-RPC specification requires the User-Agent and Host headers
-// which the kolo/xmlrpc library doesn't honor.  Aleks doesn't appear
+// which some XML-RPC libraries don't honor.  Aleks doesn't appear
 // to care if they're missing but we're adding them here for completeness.
 // More importantly, this intercepter replaces the default transport
 // with one that has compression disabled.  On the response side, the
-// XML-RPC library doesn't deal with string values wrapped in CDATA
-// tags so this RoundTripper also strips those tags from the result.
+// XML-RPC libraries don't always deal with string values wrapped in CDATA
+// sections so this RoundTripper also converts those sections into the
+// equivalent (escaped) character data.  The sections are converted as
+// the body is read, rather than buffering the entire body, and closing
+// the returned body closes the original.  As the conversion changes the
+// body's length, the returned response's ContentLength is -1 and its
+// Content-Length header is removed.
 func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Set the headers required by the specification
 	req.Header["Accept"] = []string{"*/*"}
-	req.Header["User-Agent"] = []string{"aleks-client"}
+	ua := rt.UserAgent
+	if ua == "" {
+		ua = AleksDefaultUserAgent
+	}
+	req.Header["User-Agent"] = []string{ua}
 	host := req.URL.Hostname()
 	req.Header["Host"] = []string{host}
 
@@ -70,15 +103,142 @@ func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 		return resp, err
 	}
 
-	// Strip CDATA tags
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return resp, err
+	// Enforce the maximum size
+	var body io.Reader = resp.Body
+	if rt.MaxResponseSize > 0 {
+		if resp.ContentLength > rt.MaxResponseSize {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %d byte limit, %d byte Content-Length", ErrResponseTooLarge, rt.MaxResponseSize, resp.ContentLength)
+		}
+		body = &maxSizeReader{r: body, remaining: rt.MaxResponseSize, limit: rt.MaxResponseSize}
+	}
+
+	// Convert CDATA sections
+	resp.Body = &readCloser{
+		Reader: &cdataConverter{r: body},
+		Closer: resp.Body,
 	}
-	bodyStr := string(body)
-	bodyStr = strings.ReplaceAll(bodyStr, "<![CDATA[", "")
-	bodyStr = strings.ReplaceAll(bodyStr, "]]>", "")
-	resp.Body = ioutil.NopCloser(strings.NewReader(bodyStr))
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
 
-	return resp, err
+	return resp, nil
+}
+
+// readCloser combines a filtering reader with the Closer of the
+// original response body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// maxSizeReader returns an error wrapping ErrResponseTooLarge, rather
+// than io.EOF, once more than its limit of bytes have been read.
+type maxSizeReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, fmt.Errorf("%w: %d byte limit", ErrResponseTooLarge, m.limit)
+	}
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n + int(m.remaining), fmt.Errorf("%w: %d byte limit", ErrResponseTooLarge, m.limit)
+	}
+	return n, err
+}
+
+// cdataConverter converts the CDATA sections in the stream it reads
+// into equivalent character data by removing the CDATA delimiters and
+// escaping the XML special characters within each section.  The text
+// outside of CDATA sections is passed through unaltered.  Input that
+// could be the beginning of a delimiter split across reads is held back
+// until it can be resolved.
+type cdataConverter struct {
+	r      io.Reader
+	chunk  []byte
+	in     []byte
+	out    []byte
+	err    error
+	inside bool
+}
+
+func (s *cdataConverter) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.chunk == nil {
+			s.chunk = make([]byte, cdataChunkSize)
+		}
+		n, err := s.r.Read(s.chunk)
+		s.in = append(s.in, s.chunk[:n]...)
+		s.err = err
+		s.convert(err != nil)
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// convert moves the pending input to the output.  If the input isn't
+// final, any trailing partial delimiter is retained.
+func (s *cdataConverter) convert(final bool) {
+	for {
+		delim := cdataStart
+		if s.inside {
+			delim = cdataEnd
+		}
+		idx := bytes.Index(s.in, []byte(delim))
+		if idx < 0 {
+			keep := 0
+			if !final {
+				keep = partialDelimiter(s.in, delim)
+			}
+			s.emit(s.in[:len(s.in)-keep])
+			s.in = append(s.in[:0], s.in[len(s.in)-keep:]...)
+			return
+		}
+		s.emit(s.in[:idx])
+		s.in = s.in[idx+len(delim):]
+		s.inside = !s.inside
+	}
+}
+
+// emit appends the provided bytes to the output, escaping them if they
+// are part of a CDATA section.
+func (s *cdataConverter) emit(b []byte) {
+	if !s.inside {
+		s.out = append(s.out, b...)
+		return
+	}
+	for _, c := range b {
+		switch c {
+		case '&':
+			s.out = append(s.out, "&amp;"...)
+		case '<':
+			s.out = append(s.out, "&lt;"...)
+		case '>':
+			s.out = append(s.out, "&gt;"...)
+		default:
+			s.out = append(s.out, c)
+		}
+	}
+}
+
+// partialDelimiter returns the length of the longest suffix of the
+// provided bytes that is a prefix of the delimiter.
+func partialDelimiter(b []byte, delim string) int {
+	for n := len(delim) - 1; n > 0; n-- {
+		if bytes.HasSuffix(b, []byte(delim[:n])) {
+			return n
+		}
+	}
+	return 0
 }
@@ -17,11 +17,14 @@ limitations under the License.
 package aleks
 
 import (
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -79,3 +82,97 @@ func TestRoundTripper(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "This is a test", string(respBody))
 }
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCDATAConverter(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected string
+	}{
+		{"No CDATA", "<string>This is a test</string>", "<string>This is a test</string>"},
+		{"CDATA", "<string><![CDATA[This is a test]]></string>", "<string>This is a test</string>"},
+		{"Multiple CDATA", "<![CDATA[a]]><![CDATA[b]]>", "ab"},
+		{"Partial delimiters", "<![CDAT ]] <!", "<![CDAT ]] <!"},
+		{"Trailing partial delimiter", "test]]", "test]]"},
+		{"Ampersand", "<string><![CDATA[Smith & Jones]]></string>", "<string>Smith &amp; Jones</string>"},
+		{"Angle brackets", "<![CDATA[<b>1 > 0</b>]]>", "&lt;b&gt;1 &gt; 0&lt;/b&gt;"},
+		{"Nested start marker", "<![CDATA[a <![CDATA[b]]>", "a &lt;![CDATA[b"},
+		{"Partial end marker", "<![CDATA[a]] ]]]>", "a]] ]"},
+		{"Split end marker", "<![CDATA[a]]]]><![CDATA[>]]>", "a]]&gt;"},
+		{"End marker outside CDATA", "a]]>b", "a]]>b"},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			// Read one byte at a time to split the delimiters across reads
+			s := &cdataConverter{r: iotest.OneByteReader(strings.NewReader(test.Input))}
+			out, err := ioutil.ReadAll(iotest.OneByteReader(s))
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, string(out))
+		})
+	}
+}
+
+func TestRoundTripperStreaming(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("<![CDATA[This is a test]]>")}
+	rrt := InterceptingRoundTripper{
+		T: t,
+		Response: &http.Response{
+			Header:        http.Header{"Content-Length": []string{"27"}},
+			ContentLength: 27,
+			Body:          body,
+		},
+	}
+	art := &RoundTripper{Trans: &rrt}
+	url, err := url.Parse("https://example.com/random/path")
+	require.NoError(t, err)
+
+	resp, err := art.RoundTrip(&http.Request{Header: map[string][]string{}, URL: url})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), resp.ContentLength)
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+	respBody, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "This is a test", string(respBody))
+	assert.NoError(t, resp.Body.Close())
+	assert.True(t, body.closed)
+}
+
+func TestRoundTripperMaxResponseSize(t *testing.T) {
+	url, err := url.Parse("https://example.com/random/path")
+	require.NoError(t, err)
+
+	// Declared by the Content-Length header
+	body := &closeRecorder{Reader: strings.NewReader("<![CDATA[This is a test]]>")}
+	rrt := InterceptingRoundTripper{T: t, Response: &http.Response{ContentLength: 27, Body: body}}
+	art := &RoundTripper{Trans: &rrt, MaxResponseSize: 20}
+	_, err = art.RoundTrip(&http.Request{Header: map[string][]string{}, URL: url})
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	assert.True(t, body.closed)
+
+	// Discovered while reading
+	rrt.Response = &http.Response{ContentLength: -1, Body: ioutil.NopCloser(strings.NewReader("<![CDATA[This is a test]]>"))}
+	resp, err := art.RoundTrip(&http.Request{Header: map[string][]string{}, URL: url})
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(resp.Body)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+
+	// Exactly the maximum size
+	art.MaxResponseSize = 26
+	rrt.Response = &http.Response{ContentLength: -1, Body: ioutil.NopCloser(strings.NewReader("<![CDATA[This is a test]]>"))}
+	resp, err = art.RoundTrip(&http.Request{Header: map[string][]string{}, URL: url})
+	require.NoError(t, err)
+	respBody, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "This is a test", string(respBody))
+}
@@ -0,0 +1,520 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xmlrpcDateTimeFormat = "20060102T15:04:05"
+)
+
+var xmlrpcDateTimeFormats = []string{
+	xmlrpcDateTimeFormat,
+	"20060102T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// errElementEnded is returned by nextStartElement when the enclosing
+// element ends before the next start element.
+var errElementEnded = errors.New("XML-RPC element ended")
+
+// encodeMethodCall writes an XML-RPC methodCall element describing a
+// call to the named method with the provided parameters.  Parameters
+// may be strings, integers, floats, booleans, time.Times, []bytes
+// (encoded as base64), slices or arrays, maps with string keys and
+// structs (whose exported fields are named by their "xmlrpc" tags or,
+// if untagged, their Go names) of any of these types.
+func encodeMethodCall(w io.Writer, method string, params ...interface{}) error {
+	b := bytes.Buffer{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><methodCall><methodName>`)
+	if err := xml.EscapeText(&b, []byte(method)); err != nil {
+		return err
+	}
+	b.WriteString("</methodName><params>")
+	for _, param := range params {
+		b.WriteString("<param>")
+		if err := encodeValue(&b, reflect.ValueOf(param)); err != nil {
+			return err
+		}
+		b.WriteString("</param>")
+	}
+	b.WriteString("</params></methodCall>")
+	_, err := b.WriteTo(w)
+	return err
+}
+
+func encodeValue(b *bytes.Buffer, v reflect.Value) error {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+	b.WriteString("<value>")
+	defer b.WriteString("</value>")
+	if !v.IsValid() {
+		b.WriteString("<nil/>")
+		return nil
+	}
+	if v.Type() == timeType {
+		b.WriteString("<dateTime.iso8601>" + v.Interface().(time.Time).Format(xmlrpcDateTimeFormat) + "</dateTime.iso8601>")
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		b.WriteString("<string>")
+		if err := xml.EscapeText(b, []byte(v.String())); err != nil {
+			return err
+		}
+		b.WriteString("</string>")
+	case reflect.Bool:
+		if v.Bool() {
+			b.WriteString("<boolean>1</boolean>")
+		} else {
+			b.WriteString("<boolean>0</boolean>")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString("<int>" + strconv.FormatInt(v.Int(), 10) + "</int>")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString("<int>" + strconv.FormatUint(v.Uint(), 10) + "</int>")
+	case reflect.Float32, reflect.Float64:
+		b.WriteString("<double>" + strconv.FormatFloat(v.Float(), 'f', -1, 64) + "</double>")
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			b.WriteString("<base64>" + base64.StdEncoding.EncodeToString(v.Bytes()) + "</base64>")
+			return nil
+		}
+		b.WriteString("<array><data>")
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteString("</data></array>")
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("XML-RPC struct keys must be strings, not %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		b.WriteString("<struct>")
+		for _, k := range keys {
+			if err := encodeMember(b, k.String(), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		b.WriteString("</struct>")
+	case reflect.Struct:
+		b.WriteString("<struct>")
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := memberName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if err := encodeMember(b, name, v.Field(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteString("</struct>")
+	default:
+		return fmt.Errorf("can't encode %s as an XML-RPC value", v.Type())
+	}
+	return nil
+}
+
+func encodeMember(b *bytes.Buffer, name string, v reflect.Value) error {
+	b.WriteString("<member><name>")
+	if err := xml.EscapeText(b, []byte(name)); err != nil {
+		return err
+	}
+	b.WriteString("</name>")
+	if err := encodeValue(b, v); err != nil {
+		return err
+	}
+	b.WriteString("</member>")
+	return nil
+}
+
+// memberName returns the XML-RPC struct member name of the provided
+// field and whether the field is encoded at all.
+func memberName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag := f.Tag.Get("xmlrpc")
+	if tag == "-" {
+		return "", false
+	}
+	if tag != "" {
+		return tag, true
+	}
+	return f.Name, true
+}
+
+// decodeMethodResponse reads an XML-RPC methodResponse element from the
+// provided reader and stores its value in the value pointed to by
+// result (which may be nil to discard the value).  Faults are returned
+// as an *AleksFault.  CDATA sections are treated as the equivalent
+// character data.
+func decodeMethodResponse(r io.Reader, result interface{}) error {
+	d := xml.NewDecoder(r)
+	if _, err := nextStartElement(d, "methodResponse"); err != nil {
+		return err
+	}
+	start, err := nextStartElement(d, "params", "fault")
+	if err != nil {
+		return err
+	}
+	if start.Name.Local == "fault" {
+		return decodeFaultValue(d)
+	}
+	if _, err := nextStartElement(d, "param"); err != nil {
+		return err
+	}
+	if _, err := nextStartElement(d, "value"); err != nil {
+		return err
+	}
+	v, err := decodeValue(d)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("XML-RPC result must be a non-nil pointer")
+	}
+	return assignValue(rv.Elem(), v)
+}
+
+// decodeFaultValue decodes the value of a fault element whose start has
+// already been read.
+func decodeFaultValue(d *xml.Decoder) error {
+	if _, err := nextStartElement(d, "value"); err != nil {
+		return err
+	}
+	v, err := decodeValue(d)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("XML-RPC fault value is not a struct")
+	}
+	return newAleksFault(m)
+}
+
+// newAleksFault returns the fault described by the faultCode and
+// faultString members of a decoded XML-RPC struct.
+func newAleksFault(m map[string]interface{}) *AleksFault {
+	f := &AleksFault{}
+	switch code := m["faultCode"].(type) {
+	case int64:
+		f.Code = int(code)
+	case string:
+		f.Code, _ = strconv.Atoi(strings.TrimSpace(code))
+	}
+	f.Message, _ = m["faultString"].(string)
+	return f
+}
+
+// nextStartElement skips tokens until it reads the start of one of the
+// named elements.  An error is returned if the enclosing element ends
+// first.
+func nextStartElement(d *xml.Decoder, names ...string) (xml.StartElement, error) {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return xml.StartElement{}, fmt.Errorf("XML-RPC response ended before %s element: %w", strings.Join(names, "/"), io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, name := range names {
+				if t.Name.Local == name {
+					return t, nil
+				}
+			}
+			return t, fmt.Errorf("unexpected XML-RPC element %s, expected %s", t.Name.Local, strings.Join(names, "/"))
+		case xml.EndElement:
+			return xml.StartElement{}, fmt.Errorf("%w: %s ended before %s", errElementEnded, t.Name.Local, strings.Join(names, "/"))
+		}
+	}
+}
+
+// decodeValue decodes the contents of a value element whose start has
+// already been read, including its end.  The returned value is one of
+// string, int64, bool, float64, time.Time, []byte, []interface{},
+// map[string]interface{} or nil.
+func decodeValue(d *xml.Decoder) (interface{}, error) {
+	text := strings.Builder{}
+	typed := false
+	var v interface{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			if typed {
+				return nil, fmt.Errorf("unexpected XML-RPC element %s in value", t.Name.Local)
+			}
+			typed = true
+			v, err = decodeTypedValue(d, t)
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			// Untyped values are strings
+			if !typed {
+				return text.String(), nil
+			}
+			return v, nil
+		}
+	}
+}
+
+func decodeTypedValue(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "struct":
+		return decodeStruct(d)
+	case "array":
+		return decodeArray(d)
+	case "nil":
+		return nil, d.Skip()
+	}
+	s := ""
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return s, nil
+	case "int", "i4", "i8":
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case "boolean":
+		switch strings.TrimSpace(s) {
+		case "1":
+			return true, nil
+		case "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid XML-RPC boolean: %s", s)
+	case "double":
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case "dateTime.iso8601":
+		for _, layout := range xmlrpcDateTimeFormats {
+			if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid XML-RPC dateTime.iso8601: %s", s)
+	case "base64":
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	}
+	return nil, fmt.Errorf("unsupported XML-RPC type: %s", start.Name.Local)
+}
+
+func decodeStruct(d *xml.Decoder) (interface{}, error) {
+	m := map[string]interface{}{}
+	for {
+		_, err := nextStartElement(d, "member")
+		if err != nil {
+			if errors.Is(err, errElementEnded) {
+				return m, nil
+			}
+			return nil, err
+		}
+		name := ""
+		start, err := nextStartElement(d, "name")
+		if err != nil {
+			return nil, err
+		}
+		if err := d.DecodeElement(&name, &start); err != nil {
+			return nil, err
+		}
+		if _, err := nextStartElement(d, "value"); err != nil {
+			return nil, err
+		}
+		v, err := decodeValue(d)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = v
+		if err := skipToEnd(d); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func decodeArray(d *xml.Decoder) (interface{}, error) {
+	a := []interface{}{}
+	if _, err := nextStartElement(d, "data"); err != nil {
+		return nil, err
+	}
+	for {
+		_, err := nextStartElement(d, "value")
+		if err != nil {
+			if errors.Is(err, errElementEnded) {
+				// The end of the data element was read, skip the end of
+				// the array element
+				return a, skipToEnd(d)
+			}
+			return nil, err
+		}
+		v, err := decodeValue(d)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+}
+
+// skipToEnd reads tokens until the end of the current element.
+func skipToEnd(d *xml.Decoder) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return fmt.Errorf("unexpected XML-RPC element %s", t.Name.Local)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// assignValue stores a decoded XML-RPC value in the provided settable
+// Go value, converting between compatible types.
+func assignValue(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	src := reflect.ValueOf(v)
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(src)
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignValue(dst.Elem(), v)
+	}
+	mismatch := fmt.Errorf("can't decode XML-RPC %T into %s", v, dst.Type())
+	switch val := v.(type) {
+	case string:
+		if dst.Kind() != reflect.String {
+			return mismatch
+		}
+		dst.SetString(val)
+	case int64:
+		switch dst.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetInt(val)
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(float64(val))
+		default:
+			return mismatch
+		}
+	case float64:
+		if dst.Kind() != reflect.Float32 && dst.Kind() != reflect.Float64 {
+			return mismatch
+		}
+		dst.SetFloat(val)
+	case bool:
+		if dst.Kind() != reflect.Bool {
+			return mismatch
+		}
+		dst.SetBool(val)
+	case time.Time, []byte:
+		if !src.Type().AssignableTo(dst.Type()) {
+			return mismatch
+		}
+		dst.Set(src)
+	case []interface{}:
+		if dst.Kind() != reflect.Slice {
+			return mismatch
+		}
+		s := reflect.MakeSlice(dst.Type(), len(val), len(val))
+		for i, elem := range val {
+			if err := assignValue(s.Index(i), elem); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+	case map[string]interface{}:
+		return assignStruct(dst, val, mismatch)
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+func assignStruct(dst reflect.Value, m map[string]interface{}, mismatch error) error {
+	switch dst.Kind() {
+	case reflect.Map:
+		if dst.Type().Key().Kind() != reflect.String {
+			return mismatch
+		}
+		mv := reflect.MakeMapWithSize(dst.Type(), len(m))
+		for k, elem := range m {
+			ev := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignValue(ev, elem); err != nil {
+				return err
+			}
+			mv.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), ev)
+		}
+		dst.Set(mv)
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			name, ok := memberName(dst.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if elem, ok := m[name]; ok {
+				if err := assignValue(dst.Field(i), elem); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aleks
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeMethodCall(t *testing.T) {
+	type params struct {
+		Name    string `xmlrpc:"name"`
+		Count   int    `xmlrpc:"count"`
+		Skipped string `xmlrpc:"-"`
+		hidden  string
+	}
+	b := bytes.Buffer{}
+	err := encodeMethodCall(&b, "test.method",
+		map[string]string{"b": "Smith & Jones", "a": "<1>"},
+		params{Name: "x", Count: 3, Skipped: "y", hidden: "z"},
+		[]interface{}{true, 1.5, []byte("hi"), time.Date(2019, 10, 1, 13, 4, 5, 0, time.UTC)},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?><methodCall><methodName>test.method</methodName><params>`+
+		`<param><value><struct>`+
+		`<member><name>a</name><value><string>&lt;1&gt;</string></value></member>`+
+		`<member><name>b</name><value><string>Smith &amp; Jones</string></value></member>`+
+		`</struct></value></param>`+
+		`<param><value><struct>`+
+		`<member><name>name</name><value><string>x</string></value></member>`+
+		`<member><name>count</name><value><int>3</int></value></member>`+
+		`</struct></value></param>`+
+		`<param><value><array><data>`+
+		`<value><boolean>1</boolean></value>`+
+		`<value><double>1.5</double></value>`+
+		`<value><base64>aGk=</base64></value>`+
+		`<value><dateTime.iso8601>20191001T13:04:05</dateTime.iso8601></value>`+
+		`</data></array></value></param>`+
+		`</params></methodCall>`, b.String())
+
+	assert.Error(t, encodeMethodCall(&b, "test.method", make(chan int)))
+	assert.Error(t, encodeMethodCall(&b, "test.method", map[int]string{}))
+}
+
+func TestDecodeMethodResponse(t *testing.T) {
+	type result struct {
+		Name   string   `xmlrpc:"name"`
+		Count  int      `xmlrpc:"count"`
+		Scores []int    `xmlrpc:"scores"`
+		Ratio  float64  `xmlrpc:"ratio"`
+		Active bool     `xmlrpc:"active"`
+		Tags   []string `xmlrpc:"tags"`
+	}
+	body := `<?xml version="1.0"?>
+<methodResponse>
+  <params>
+    <param>
+      <value><struct>
+        <member><name>name</name><value><![CDATA[Smith & Jones <Jr.>]]></value></member>
+        <member><name>count</name><value><i4>3</i4></value></member>
+        <member><name>scores</name><value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value></member>
+        <member><name>ratio</name><value><double>0.5</double></value></member>
+        <member><name>active</name><value><boolean>1</boolean></value></member>
+        <member><name>tags</name><value><array><data></data></array></value></member>
+        <member><name>unknown</name><value><string>ignored</string></value></member>
+      </struct></value>
+    </param>
+  </params>
+</methodResponse>`
+	r := result{}
+	require.NoError(t, decodeMethodResponse(strings.NewReader(body), &r))
+	assert.Equal(t, result{
+		Name:   "Smith & Jones <Jr.>",
+		Count:  3,
+		Scores: []int{1, 2},
+		Ratio:  0.5,
+		Active: true,
+		Tags:   []string{},
+	}, r)
+
+	var m map[string]interface{}
+	require.NoError(t, decodeMethodResponse(strings.NewReader(body), &m))
+	assert.Equal(t, int64(3), m["count"])
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, m["scores"])
+
+	require.NoError(t, decodeMethodResponse(strings.NewReader(body), nil))
+}
+
+func TestDecodeMethodResponseString(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Value    string
+		Expected string
+	}{
+		{"Untyped", `<value>plain</value>`, "plain"},
+		{"Typed", `<value><string>typed</string></value>`, "typed"},
+		{"Escaped", `<value><string>a &amp; b &lt; c</string></value>`, "a & b < c"},
+		{"CDATA", `<value><string><![CDATA[a & b < c]]></string></value>`, "a & b < c"},
+		{"Split CDATA", `<value><string><![CDATA[a ]]]]><![CDATA[> b]]></string></value>`, "a ]]> b"},
+		{"Empty", `<value><string></string></value>`, ""},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			body := `<methodResponse><params><param>` + test.Value + `</param></params></methodResponse>`
+			s := ""
+			require.NoError(t, decodeMethodResponse(strings.NewReader(body), &s))
+			assert.Equal(t, test.Expected, s)
+		})
+	}
+}
+
+func TestDecodeMethodResponseFault(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<methodResponse><fault><value><struct>
+  <member><name>faultCode</name><value><int>2</int></value></member>
+  <member><name>faultString</name><value><string>Invalid class code: ABCDE-FGHIJ</string></value></member>
+</struct></value></fault></methodResponse>`
+	s := ""
+	err := decodeMethodResponse(strings.NewReader(body), &s)
+	var fault *AleksFault
+	require.True(t, errors.As(err, &fault))
+	assert.Equal(t, &AleksFault{Code: 2, Message: "Invalid class code: ABCDE-FGHIJ"}, fault)
+	assert.True(t, errors.Is(err, ErrUnknownClasscode))
+}
+
+func TestDecodeMethodResponseErrors(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Body   string
+		Result interface{}
+	}{
+		{"Not XML-RPC", `<html><body>Oops</body></html>`, new(string)},
+		{"Empty params", `<methodResponse><params></params></methodResponse>`, new(string)},
+		{"Type mismatch", `<methodResponse><params><param><value><int>1</int></value></param></params></methodResponse>`, new(string)},
+		{"Invalid int", `<methodResponse><params><param><value><int>one</int></value></param></params></methodResponse>`, new(int)},
+		{"Unsupported type", `<methodResponse><params><param><value><bogus>1</bogus></value></param></params></methodResponse>`, new(string)},
+		{"Non-pointer result", `<methodResponse><params><param><value>x</value></param></params></methodResponse>`, ""},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Error(t, decodeMethodResponse(strings.NewReader(test.Body), test.Result))
+		})
+	}
+}
+
+func TestDecodeMethodResponseTruncated(t *testing.T) {
+	body := `<methodResponse><params><param><value><string>partial`
+	s := ""
+	err := decodeMethodResponse(io.MultiReader(strings.NewReader(body), errReader{io.ErrUnexpectedEOF}), &s)
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), err)
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"string": "Smith & Jones",
+		"int":    int64(-7),
+		"bool":   false,
+		"double": 2.25,
+		"array":  []interface{}{"a", int64(1)},
+		"struct": map[string]interface{}{"nested": "value"},
+	}
+	call := bytes.Buffer{}
+	require.NoError(t, encodeMethodCall(&call, "echo", in))
+
+	// Turn the methodCall into an equivalent methodResponse
+	body := call.String()
+	body = body[strings.Index(body, "<params>"):strings.Index(body, "</methodCall>")]
+	out := map[string]interface{}{}
+	require.NoError(t, decodeMethodResponse(strings.NewReader("<methodResponse>"+body+"</methodResponse>"), &out))
+	assert.Equal(t, in, out)
+}
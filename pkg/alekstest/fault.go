@@ -0,0 +1,175 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alekstest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/PennState/aleks-client/pkg/aleks"
+)
+
+// Fault describes misbehavior injected into the Server's responses to
+// getPlacementReport calls.  The zero value injects no faults and each
+// field can be combined with the others except where noted.  When calls
+// are batched by system.multicall, the Faults injected into them are
+// combined: their latencies are summed, the first StatusCode and the
+// smallest Truncate apply to the whole response and XML-RPC faults
+// replace the results of the individual calls.
+type Fault struct {
+	// Latency delays the response by the provided duration unless the
+	// client abandons the request first.
+	Latency time.Duration
+
+	// StatusCode, if non-zero, is returned as the HTTP status in place
+	// of the XML-RPC response.
+	StatusCode int
+
+	// FaultCode and FaultString, if the latter is not empty, are
+	// returned as an XML-RPC fault in place of the XML-RPC response.
+	FaultCode   int
+	FaultString string
+
+	// Truncate, if non-zero, limits the response body to the provided
+	// number of bytes even though the Content-Length header describes
+	// the complete body.
+	Truncate int
+
+	// MalformedRow adds a row with too few columns to the page.
+	MalformedRow bool
+
+	// WrongHeaders replaces the page's header row with unexpected
+	// column titles, none of which are recognized.
+	WrongHeaders bool
+
+	// NoEndMarker repeats the last page of records, rather than
+	// returning the end marker, when a page past the last is requested.
+	NoEndMarker bool
+
+	// Times limits the number of responses the fault is injected into.
+	// Zero injects the fault into every matching response.
+	Times int
+}
+
+type injectedFault struct {
+	Fault
+	classcode string
+	page      int
+	remaining int
+}
+
+// InjectFault adds a Fault to the Server's getPlacementReport responses
+// for the provided class-code and page.  An empty class-code matches
+// every class-code and a page of zero matches every page.  When more
+// than one injected fault matches a call, the first one that was added
+// is used.
+func (s *Server) InjectFault(classcode string, page int, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &injectedFault{
+		Fault:     f,
+		classcode: classcode,
+		page:      page,
+		remaining: f.Times,
+	})
+}
+
+// ClearFaults removes every Fault added by InjectFault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// fault returns the Fault to inject into the response for the provided
+// class-code and page.
+func (s *Server) fault(classcode string, page int) Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for idx, f := range s.faults {
+		if (f.classcode != "" && f.classcode != classcode) || (f.page != 0 && f.page != page) {
+			continue
+		}
+		if f.Times > 0 {
+			f.remaining--
+			if f.remaining == 0 {
+				s.faults = append(s.faults[:idx:idx], s.faults[idx+1:]...)
+			}
+		}
+		return f.Fault
+	}
+	return Fault{}
+}
+
+// apply injects the faults that replace the normal response and reports
+// whether the response should still be written.
+func (f Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	if f.StatusCode != 0 {
+		http.Error(w, http.StatusText(f.StatusCode), f.StatusCode)
+		return false
+	}
+	if f.FaultString != "" {
+		writeFault(w, f.FaultCode, f.FaultString)
+		return false
+	}
+	return true
+}
+
+// formatPlacementRecords behaves like FormatPlacementRecords but
+// injects the faults that alter the CSV formatted page.
+func (f Fault) formatPlacementRecords(recs []aleks.PlacementRecord) string {
+	rows := placementRecordRows(recs)
+	if f.WrongHeaders {
+		for idx := range rows[0] {
+			rows[0][idx] = fmt.Sprintf("Column %d", idx+1)
+		}
+	}
+	if f.MalformedRow {
+		rows = append(rows, []string{"Doe, Malformed", "900000000"})
+	}
+	return formatCSV(rows)
+}
+
+// write writes the XML-RPC response produced by the provided function,
+// injecting the faults that alter the response body.
+func (f Fault) write(w http.ResponseWriter, fn func(io.Writer)) {
+	w.Header().Set("Content-Type", "text/xml")
+	if f.Truncate == 0 {
+		fn(w)
+		return
+	}
+	buf := bytes.Buffer{}
+	fn(&buf)
+	body := buf.Bytes()
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	if f.Truncate < len(body) {
+		body = body[:f.Truncate]
+	}
+	_, _ = w.Write(body)
+}
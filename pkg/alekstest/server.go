@@ -0,0 +1,535 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package alekstest provides an in-process fake of the Aleks XML-RPC
+service for use in tests.  The fake implements the getPlacementReport
+method, including its pagination, end marker and CDATA wrapped
+responses, using placement records registered per class-code, along
+with the system.multicall method and the system.listMethods,
+system.methodHelp and system.methodSignature introspection methods.  Faults
+such as latency, HTTP errors, XML-RPC faults and malformed pages can be
+injected into its responses to verify how clients handle a misbehaving
+service.
+*/
+package alekstest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PennState/aleks-client/pkg/aleks"
+)
+
+const (
+	// DefaultPageSize is the number of placement records returned in
+	// each page unless the Server's PageSize is changed.
+	DefaultPageSize = 10
+
+	// EndMarker is returned in place of a page of records once every
+	// record matching a request has been returned.
+	EndMarker = "No records found"
+
+	// FaultMethodNotFound is the XML-RPC fault code returned when an
+	// unknown method is called.
+	FaultMethodNotFound = -32601
+
+	// FaultInvalidParams is the XML-RPC fault code returned when a call's
+	// parameters are missing or malformed.
+	FaultInvalidParams = -32602
+
+	// FaultAuthentication is the XML-RPC fault code returned when the
+	// username or password is incorrect.  The Aleks service's fault
+	// codes aren't documented so this and the following codes are
+	// arbitrary - the aleks package classifies the Server's faults by
+	// their messages, which are those that it recognizes.
+	FaultAuthentication = 1
+
+	// FaultUnknownClasscode is the XML-RPC fault code returned when no
+	// placement records have been registered for a class-code.
+	FaultUnknownClasscode = 2
+
+	// FaultInvalidDateRange is the XML-RPC fault code returned when the
+	// completion dates can't be parsed or are out of order.
+	FaultInvalidDateRange = 3
+
+	placementReportMethod = "getPlacementReport"
+	multicallMethod       = "system.multicall"
+	listMethodsMethod     = "system.listMethods"
+	methodHelpMethod      = "system.methodHelp"
+	methodSignatureMethod = "system.methodSignature"
+	requestDateFormat     = "2006-01-02"
+	recordDateFormat      = "01/02/2006"
+	recordTimeFormat      = "03:04 PM"
+)
+
+// Server is a fake Aleks XML-RPC service listening on a loopback
+// address.  Its URL should be passed to aleks.NewClient along with its
+// Username and Password.  The exported fields may be changed before the
+// first call is made to the Server.  Setting DisableMulticall or
+// DisableIntrospection causes calls to system.multicall or to the
+// introspection methods, respectively, to fail as if the methods didn't
+// exist.  If Location is set, the records' dates and times are reported
+// (and the completion date range is applied) in that time zone, as the
+// Aleks service reports local times, rather than in their own.
+type Server struct {
+	*httptest.Server
+	Username             string
+	Password             string
+	PageSize             int
+	DisableMulticall     bool
+	DisableIntrospection bool
+	Location             *time.Location
+
+	mu      sync.Mutex
+	records map[string][]aleks.PlacementRecord
+	faults  []*injectedFault
+	calls   int
+}
+
+// NewServer starts and returns a new Server that accepts the provided
+// credentials.  The caller should call Close when finished to shut it
+// down.
+func NewServer(username, password string) *Server {
+	s := &Server{
+		Username: username,
+		Password: password,
+		PageSize: DefaultPageSize,
+		records:  map[string][]aleks.PlacementRecord{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// AddPlacementRecords registers the class-code (if necessary) and adds
+// the provided records to those returned for it.  A record is included
+// in a report if the date of its EndTime falls within the requested
+// completion date range.  Calling AddPlacementRecords without records
+// registers a class-code that has no placement records.
+func (s *Server) AddPlacementRecords(classcode string, recs ...aleks.PlacementRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[classcode] = append(s.records[classcode], recs...)
+}
+
+// Calls returns the number of XML-RPC calls the Server has received.
+func (s *Server) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "XML-RPC calls must use the POST method", http.StatusMethodNotAllowed)
+		return
+	}
+	mc := methodCall{}
+	if err := xml.NewDecoder(r.Body).Decode(&mc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/xml")
+	switch {
+	case mc.MethodName == placementReportMethod:
+		f, res := s.getPlacementReport(mc.Params)
+		if !f.apply(w, r) {
+			return
+		}
+		if res.FaultString != "" {
+			writeFault(w, res.FaultCode, res.FaultString)
+			return
+		}
+		f.write(w, func(w io.Writer) { writeResponse(w, res.Value) })
+	case mc.MethodName == multicallMethod && !s.DisableMulticall:
+		s.multicall(w, r, mc.Params)
+	case mc.MethodName == listMethodsMethod && !s.DisableIntrospection:
+		names := []string{}
+		for _, m := range s.methods() {
+			names = append(names, m.name)
+		}
+		sort.Strings(names)
+		writeValue(w, stringArray(names))
+	case (mc.MethodName == methodHelpMethod || mc.MethodName == methodSignatureMethod) && !s.DisableIntrospection:
+		if len(mc.Params) != 1 {
+			writeFault(w, FaultInvalidParams, "Expected a single string parameter")
+			return
+		}
+		m, ok := s.methods()[mc.Params[0].text()]
+		if !ok {
+			writeFault(w, FaultInvalidParams, "Unknown method: "+mc.Params[0].text())
+			return
+		}
+		if mc.MethodName == methodHelpMethod {
+			writeValue(w, cdataString(m.help))
+			return
+		}
+		sigs := []string{}
+		for _, sig := range m.signatures {
+			sigs = append(sigs, stringArray(sig))
+		}
+		writeValue(w, "<array><data><value>"+strings.Join(sigs, "</value><value>")+"</value></data></array>")
+	default:
+		writeFault(w, FaultMethodNotFound, "Method not found: "+mc.MethodName)
+	}
+}
+
+// method describes one of the Server's XML-RPC methods.
+type method struct {
+	name       string
+	help       string
+	signatures [][]string
+}
+
+// methods returns the methods currently provided by the Server, indexed
+// by name.
+func (s *Server) methods() map[string]method {
+	all := []method{
+		{placementReportMethod, "Returns a page of the CSV formatted placement report for a class code and completion date range.", [][]string{{"string", "struct"}}},
+	}
+	if !s.DisableMulticall {
+		all = append(all, method{multicallMethod, "Makes several XML-RPC calls in a single request.", [][]string{{"array", "array"}}})
+	}
+	all = append(all,
+		method{listMethodsMethod, "Returns the names of the available methods.", [][]string{{"array"}}},
+		method{methodHelpMethod, "Returns the documentation of a method.", [][]string{{"string", "string"}}},
+		method{methodSignatureMethod, "Returns the signatures of a method.", [][]string{{"array", "string"}}},
+	)
+	m := map[string]method{}
+	for _, meth := range all {
+		m[meth.name] = meth
+	}
+	return m
+}
+
+// result is the outcome of a single XML-RPC call, either a string value
+// or, if FaultString isn't empty, a fault.
+type result struct {
+	Value       string
+	FaultCode   int
+	FaultString string
+}
+
+func faultResult(code int, msg string) result {
+	return result{FaultCode: code, FaultString: msg}
+}
+
+// getPlacementReport returns the Fault injected into the call along
+// with the call's result.
+func (s *Server) getPlacementReport(params []value) (Fault, result) {
+	if len(params) != 1 {
+		return Fault{}, faultResult(FaultInvalidParams, "Expected a single struct parameter")
+	}
+	p := params[0].members()
+	page, err := strconv.Atoi(p["page_num"])
+	if err != nil || page < 1 {
+		return Fault{}, faultResult(FaultInvalidParams, "Invalid page_num: "+p["page_num"])
+	}
+	f := s.fault(p["class_code"], page)
+	if p["username"] != s.Username || p["password"] != s.Password {
+		return f, faultResult(FaultAuthentication, "Invalid username or password")
+	}
+	from, err := time.Parse(requestDateFormat, p["from_completion_date"])
+	if err != nil {
+		return f, faultResult(FaultInvalidDateRange, "Invalid from_completion_date: "+p["from_completion_date"])
+	}
+	to, err := time.Parse(requestDateFormat, p["to_completion_date"])
+	if err != nil || to.Before(from) {
+		return f, faultResult(FaultInvalidDateRange, "Invalid to_completion_date: "+p["to_completion_date"])
+	}
+
+	s.mu.Lock()
+	all, ok := s.records[p["class_code"]]
+	pageSize := s.PageSize
+	s.mu.Unlock()
+	if !ok {
+		return f, faultResult(FaultUnknownClasscode, "Invalid class code: "+p["class_code"])
+	}
+
+	recs := []aleks.PlacementRecord{}
+	for _, rec := range all {
+		if s.Location != nil {
+			rec.LastLogin = rec.LastLogin.In(s.Location)
+			rec.StartTime = rec.StartTime.In(s.Location)
+			rec.EndTime = rec.EndTime.In(s.Location)
+		}
+		d := time.Date(rec.EndTime.Year(), rec.EndTime.Month(), rec.EndTime.Day(), 0, 0, 0, 0, time.UTC)
+		if !d.Before(from) && !d.After(to) {
+			recs = append(recs, rec)
+		}
+	}
+	start := (page - 1) * pageSize
+	if start >= len(recs) {
+		if !f.NoEndMarker {
+			return f, result{Value: EndMarker}
+		}
+		// Repeat the last page (if any) in place of the end marker
+		start = len(recs) - len(recs)%pageSize
+		if start == len(recs) && start > 0 {
+			start -= pageSize
+		}
+	}
+	end := start + pageSize
+	if end > len(recs) {
+		end = len(recs)
+	}
+	return f, result{Value: f.formatPlacementRecords(recs[start:end])}
+}
+
+// multicall makes each of the calls described by the single array
+// parameter and writes their results after applying the combination of
+// the Faults injected into them.
+func (s *Server) multicall(w http.ResponseWriter, r *http.Request, params []value) {
+	if len(params) != 1 || params[0].Array == nil {
+		writeFault(w, FaultInvalidParams, "Expected a single array parameter")
+		return
+	}
+	combined := Fault{}
+	results := []result{}
+	for _, call := range params[0].Array {
+		name := call.member("methodName").text()
+		if name != placementReportMethod {
+			results = append(results, faultResult(FaultMethodNotFound, "Method not found: "+name))
+			continue
+		}
+		f, res := s.getPlacementReport(call.member("params").Array)
+		combined.Latency += f.Latency
+		if combined.StatusCode == 0 {
+			combined.StatusCode = f.StatusCode
+		}
+		if f.Truncate != 0 && (combined.Truncate == 0 || f.Truncate < combined.Truncate) {
+			combined.Truncate = f.Truncate
+		}
+		if f.FaultString != "" {
+			res = faultResult(f.FaultCode, f.FaultString)
+		}
+		results = append(results, res)
+	}
+	if !combined.apply(w, r) {
+		return
+	}
+	combined.write(w, func(w io.Writer) { writeMulticallResponse(w, results) })
+}
+
+// Headers returns the column titles of the CSV formatted placement
+// report pages returned by the Aleks service.
+func Headers() []string {
+	return []string{
+		"Name",
+		"Student Id",
+		"Email",
+		"Last login",
+		"Placement Assessment Number",
+		"Total Number of Placements Taken",
+		"Start Date",
+		"Start Time",
+		"End Date",
+		"End Time",
+		"Proctored Assessment",
+		"Time in Placement (in hours)",
+		"Placement Results %",
+	}
+}
+
+// FormatPlacementRecord returns the CSV columns used by the Aleks
+// service to represent the provided record, excluding the values of its
+// Extra columns.  The ProctoredAssessment column contains the raw value,
+// if any, or one made from the Proctored value and Status otherwise.
+func FormatPlacementRecord(rec aleks.PlacementRecord) []string {
+	return []string{
+		rec.Name,
+		rec.StudentID,
+		rec.Email,
+		rec.LastLogin.Format(recordDateFormat),
+		strconv.Itoa(rec.PlacementAssessmentNumber),
+		strconv.Itoa(rec.TotalNumberOfPlacementsTaken),
+		rec.StartTime.Format(recordDateFormat),
+		rec.StartTime.Format(recordTimeFormat),
+		rec.EndTime.Format(recordDateFormat),
+		rec.EndTime.Format(recordTimeFormat),
+		rec.ProctoredAssessment.String(),
+		strconv.FormatFloat(rec.HoursInPlacement, 'f', -1, 64),
+		strconv.FormatFloat(rec.PlacementResults, 'f', -1, 64) + "%",
+	}
+}
+
+// FormatPlacementRecords returns a page of the CSV formatted placement
+// report, including its header row, containing the provided records.
+// The titles of the records' Extra columns (if any) follow the usual
+// columns in sorted order.
+func FormatPlacementRecords(recs []aleks.PlacementRecord) string {
+	return formatCSV(placementRecordRows(recs))
+}
+
+// placementRecordRows returns the header row and the rows describing
+// the provided records.
+func placementRecordRows(recs []aleks.PlacementRecord) [][]string {
+	extra := []string{}
+	seen := map[string]bool{}
+	for _, rec := range recs {
+		for title := range rec.Extra {
+			if !seen[title] {
+				seen[title] = true
+				extra = append(extra, title)
+			}
+		}
+	}
+	sort.Strings(extra)
+
+	rows := [][]string{append(Headers(), extra...)}
+	for _, rec := range recs {
+		row := FormatPlacementRecord(rec)
+		for _, title := range extra {
+			row = append(row, rec.Extra[title])
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func formatCSV(rows [][]string) string {
+	buf := bytes.Buffer{}
+	for _, row := range rows {
+		quoted := make([]string, len(row))
+		for idx, col := range row {
+			quoted[idx] = `"` + strings.ReplaceAll(col, `"`, `""`) + `"`
+		}
+		buf.WriteString(strings.Join(quoted, ",") + "\n")
+	}
+	return buf.String()
+}
+
+// methodCall is the subset of an XML-RPC request used by the Server.
+type methodCall struct {
+	MethodName string  `xml:"methodName"`
+	Params     []value `xml:"params>param>value"`
+}
+
+type value struct {
+	String *string  `xml:"string"`
+	Int    *string  `xml:"int"`
+	I4     *string  `xml:"i4"`
+	Struct []member `xml:"struct>member"`
+	Array  []value  `xml:"array>data>value"`
+	Text   string   `xml:",chardata"`
+}
+
+type member struct {
+	Name  string `xml:"name"`
+	Value value  `xml:"value"`
+}
+
+// text returns the scalar value as a string.  Untyped values are
+// strings according to the XML-RPC specification.
+func (v value) text() string {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil:
+		return *v.Int
+	case v.I4 != nil:
+		return *v.I4
+	}
+	return v.Text
+}
+
+// member returns the named member of a struct value.
+func (v value) member(name string) value {
+	for _, mem := range v.Struct {
+		if mem.Name == name {
+			return mem.Value
+		}
+	}
+	return value{}
+}
+
+// members returns the scalar members of a struct value.
+func (v value) members() map[string]string {
+	m := map[string]string{}
+	for _, mem := range v.Struct {
+		m[mem.Name] = mem.Value.text()
+	}
+	return m
+}
+
+// writeResponse writes an XML-RPC response containing a single string
+// wrapped in a CDATA section as done by the Aleks service.
+func writeResponse(w io.Writer, s string) {
+	writeValue(w, cdataString(s))
+}
+
+// writeValue writes an XML-RPC response containing the provided value.
+func writeValue(w io.Writer, v string) {
+	fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><params><param><value>%s</value></param></params></methodResponse>`, v)
+}
+
+// stringArray returns an array value containing the provided strings.
+func stringArray(values []string) string {
+	buf := bytes.Buffer{}
+	buf.WriteString("<array><data>")
+	for _, v := range values {
+		buf.WriteString("<value><string>")
+		_ = xml.EscapeText(&buf, []byte(v))
+		buf.WriteString("</string></value>")
+	}
+	buf.WriteString("</data></array>")
+	return buf.String()
+}
+
+// writeMulticallResponse writes a system.multicall response containing
+// the provided results.
+func writeMulticallResponse(w io.Writer, results []result) {
+	fmt.Fprint(w, `<?xml version="1.0"?><methodResponse><params><param><value><array><data>`)
+	for _, res := range results {
+		if res.FaultString != "" {
+			fmt.Fprintf(w, `<value>%s</value>`, faultStruct(res.FaultCode, res.FaultString))
+			continue
+		}
+		fmt.Fprintf(w, `<value><array><data><value>%s</value></data></array></value>`, cdataString(res.Value))
+	}
+	fmt.Fprint(w, `</data></array></value></param></params></methodResponse>`)
+}
+
+// cdataString returns a string value wrapped in a CDATA section.
+func cdataString(s string) string {
+	return "<string><![CDATA[" + strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>") + "]]></string>"
+}
+
+// writeFault writes an XML-RPC fault response.
+func writeFault(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><fault><value>%s</value></fault></methodResponse>`, faultStruct(code, msg))
+}
+
+// faultStruct returns the struct describing an XML-RPC fault.
+func faultStruct(code int, msg string) string {
+	esc := bytes.Buffer{}
+	_ = xml.EscapeText(&esc, []byte(msg))
+	return fmt.Sprintf(`<struct><member><name>faultCode</name><value><int>%d</int></value></member><member><name>faultString</name><value><string>%s</string></value></member></struct>`, code, esc.String())
+}
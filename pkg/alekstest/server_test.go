@@ -0,0 +1,390 @@
+/*
+Copyright 2019 The Pennsylvania State University
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alekstest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PennState/aleks-client/pkg/aleks"
+	"github.com/PennState/aleks-client/pkg/alekstest"
+)
+
+func placementRecords(count int, start time.Time) []aleks.PlacementRecord {
+	recs := []aleks.PlacementRecord{}
+	for i := 0; i < count; i++ {
+		day := start.AddDate(0, 0, i)
+		recs = append(recs, aleks.PlacementRecord{
+			Name:                         fmt.Sprintf("Doe, John %d", i),
+			StudentID:                    fmt.Sprintf("9%08d", i),
+			Email:                        fmt.Sprintf("JQD%d@PSU.EDU", i),
+			LastLogin:                    day,
+			PlacementAssessmentNumber:    1,
+			TotalNumberOfPlacementsTaken: 1,
+			StartTime:                    day.Add(13*time.Hour + 42*time.Minute),
+			EndTime:                      day.Add(15*time.Hour + 23*time.Minute),
+			ProctoredAssessment:          aleks.ProctoredAssessment{Raw: "No/Complete", Proctored: aleks.ProctoredNo, Status: aleks.AssessmentComplete},
+			HoursInPlacement:             1.7,
+			PlacementResults:             62,
+		})
+	}
+	return recs
+}
+
+func TestServerPlacementReport(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.PageSize = 4
+	recs := placementRecords(30, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs[:20]...)
+	srv.AddPlacementRecords("KLMNO-PQRST", recs[20:]...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	// March 6th through March 25th includes records 5 through 24
+	pr, errs := c.GetPlacementReport("2016-03-06", "2016-03-25", "ABCDE-FGHIJ", "KLMNO-PQRST")
+	require.Len(t, errs, 0)
+	sort.Slice(pr, func(i, j int) bool { return pr[i].StudentID < pr[j].StudentID })
+	assert.Equal(t, aleks.PlacementReport(recs[5:25]), pr)
+
+	// 15 records in four pages plus the end marker and 5 records in two
+	// pages plus the end marker.
+	assert.Equal(t, 8, srv.Calls())
+}
+
+func TestServerMulticall(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.PageSize = 4
+	recs := placementRecords(30, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs[:20]...)
+	srv.AddPlacementRecords("KLMNO-PQRST", recs[20:]...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password", aleks.WithMulticall(3))
+	require.NoError(t, err)
+
+	pages := map[string][]int{}
+	errs := c.WalkPlacementReport(context.Background(), "2016-03-06", "2016-03-25", func(classcode string, page int, rec aleks.PlacementRecord) error {
+		if n := len(pages[classcode]); n == 0 || pages[classcode][n-1] != page {
+			pages[classcode] = append(pages[classcode], page)
+		}
+		return nil
+	}, "ABCDE-FGHIJ", "KLMNO-PQRST")
+	require.Len(t, errs, 0)
+	assert.Equal(t, map[string][]int{"ABCDE-FGHIJ": {1, 2, 3, 4}, "KLMNO-PQRST": {1, 2}}, pages)
+
+	// Five pages in two batches of three and two pages plus the end
+	// marker in a single batch.
+	assert.Equal(t, 3, srv.Calls())
+}
+
+func TestServerMulticallPageConcurrency(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.PageSize = 2
+	recs := placementRecords(20, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password", aleks.WithMulticall(2), aleks.WithPageConcurrency(3))
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	assert.Equal(t, aleks.PlacementReport(recs), pr)
+
+	// Ten pages plus the end marker in two windows of three batches of
+	// two pages.
+	assert.Equal(t, 6, srv.Calls())
+}
+
+func TestServerMulticallFault(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.PageSize = 2
+	recs := placementRecords(10, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+	srv.InjectFault("ABCDE-FGHIJ", 3, alekstest.Fault{FaultCode: 99, FaultString: "Try again later"})
+
+	c, err := aleks.NewClient(srv.URL, "username", "password", aleks.WithMulticall(4))
+	require.NoError(t, err)
+
+	// The pages before the fault are retained
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, pr, 4)
+	require.Len(t, errs, 1)
+	var fault *aleks.AleksFault
+	require.True(t, errors.As(errs[0], &fault), errs[0].Error())
+	assert.Equal(t, 99, fault.Code)
+	assert.Contains(t, errs[0].Error(), "page 3")
+	assert.Equal(t, 1, srv.Calls())
+}
+
+func TestServerMulticallFallback(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.PageSize = 4
+	srv.DisableMulticall = true
+	recs := placementRecords(10, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password", aleks.WithMulticall(3))
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	assert.Equal(t, aleks.PlacementReport(recs), pr)
+
+	// The rejected multicall followed by three pages plus the end marker
+	assert.Equal(t, 5, srv.Calls())
+
+	// The rejection is remembered
+	_, errs = c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	assert.Equal(t, 9, srv.Calls())
+}
+
+func TestServerIntrospection(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	methods, err := c.ListMethods(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"getPlacementReport", "system.listMethods", "system.methodHelp", "system.methodSignature", "system.multicall"}, methods)
+
+	help, err := c.MethodHelp(ctx, "getPlacementReport")
+	require.NoError(t, err)
+	assert.Contains(t, help, "placement report")
+
+	sigs, err := c.MethodSignature(ctx, "getPlacementReport")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"string", "struct"}}, sigs)
+
+	_, err = c.MethodHelp(ctx, "unknownMethod")
+	assert.Error(t, err)
+
+	unsupported := alekstest.NewServer("username", "password")
+	defer unsupported.Close()
+	unsupported.DisableIntrospection = true
+	c, err = aleks.NewClient(unsupported.URL, "username", "password")
+	require.NoError(t, err)
+	_, err = c.ListMethods(ctx)
+	assert.True(t, errors.Is(err, aleks.ErrIntrospectionUnsupported), err)
+	_, err = c.MethodHelp(ctx, "getPlacementReport")
+	assert.True(t, errors.Is(err, aleks.ErrIntrospectionUnsupported), err)
+	_, err = c.MethodSignature(ctx, "getPlacementReport")
+	assert.True(t, errors.Is(err, aleks.ErrIntrospectionUnsupported), err)
+}
+
+func TestServerFaults(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.AddPlacementRecords("ABCDE-FGHIJ")
+
+	c, err := aleks.NewClient(srv.URL, "username", "wrong")
+	require.NoError(t, err)
+	_, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], aleks.ErrAuthentication), errs[0].Error())
+
+	c, err = aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, errs, 0)
+	assert.Len(t, pr, 0)
+
+	_, errs = c.GetPlacementReport("2016-01-01", "2016-12-31", "KLMNO-PQRST")
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], aleks.ErrUnknownClasscode), errs[0].Error())
+
+	_, errs = c.GetPlacementReport("2016-12-31", "2016-01-01", "ABCDE-FGHIJ")
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], aleks.ErrInvalidDateRange), errs[0].Error())
+}
+
+func TestServerInjectedFaults(t *testing.T) {
+	policy := aleks.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Multiplier: 1}
+	recs := placementRecords(5, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		Name    string
+		Page    int
+		Fault   alekstest.Fault
+		Records int
+		Errors  int
+		Calls   int
+	}{
+		{"Transient status", 2, alekstest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 1}, 5, 0, 5},
+		{"Persistent status", 2, alekstest.Fault{StatusCode: http.StatusInternalServerError}, 2, 1, 3},
+		{"Truncated body", 1, alekstest.Fault{Truncate: 50, Times: 1}, 5, 0, 5},
+		{"XML-RPC fault", 3, alekstest.Fault{FaultCode: 99, FaultString: "Try again later"}, 4, 1, 3},
+		{"Malformed row", 1, alekstest.Fault{MalformedRow: true}, 5, 1, 4},
+		{"Wrong headers", 2, alekstest.Fault{WrongHeaders: true}, 3, 2, 4},
+	}
+	for idx := range tests {
+		test := tests[idx]
+		t.Run(test.Name, func(t *testing.T) {
+			srv := alekstest.NewServer("username", "password")
+			defer srv.Close()
+			srv.PageSize = 2
+			srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+			srv.InjectFault("ABCDE-FGHIJ", test.Page, test.Fault)
+
+			c, err := aleks.NewClient(srv.URL, "username", "password", aleks.WithRetryPolicy(policy))
+			require.NoError(t, err)
+			pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+			assert.Len(t, pr, test.Records)
+			assert.Len(t, errs, test.Errors)
+			assert.Equal(t, test.Calls, srv.Calls())
+		})
+	}
+}
+
+func TestServerInjectedLatency(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.AddPlacementRecords("ABCDE-FGHIJ", placementRecords(1, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))...)
+	srv.InjectFault("", 2, alekstest.Fault{Latency: time.Minute})
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	pr, errs := c.GetPlacementReportContext(ctx, "2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	assert.Len(t, pr, 1)
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], context.DeadlineExceeded))
+}
+
+func TestServerInjectedNoEndMarker(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.PageSize = 2
+	srv.AddPlacementRecords("ABCDE-FGHIJ", placementRecords(3, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))...)
+	srv.InjectFault("", 0, alekstest.Fault{NoEndMarker: true})
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	ids := []string{}
+	errs := c.WalkPlacementReport(context.Background(), "2016-01-01", "2016-12-31", func(classcode string, page int, rec aleks.PlacementRecord) error {
+		ids = append(ids, rec.StudentID)
+		return nil
+	}, "ABCDE-FGHIJ")
+	// The repeated last page aborts the pagination
+	require.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], aleks.ErrRepeatedPage), errs[0].Error())
+	assert.Equal(t, []string{"900000000", "900000001", "900000002"}, ids)
+}
+
+func TestServerExtraColumns(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	recs := placementRecords(3, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	recs[0].Extra = map[string]string{"Campus": "UP"}
+	recs[2].Extra = map[string]string{"Campus": "Altoona", "Section": "002"}
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	require.Len(t, pr, 3)
+	// Every record has a value (which may be empty) for each column
+	assert.Equal(t, map[string]string{"Campus": "UP", "Section": ""}, pr[0].Extra)
+	assert.Equal(t, map[string]string{"Campus": "", "Section": ""}, pr[1].Extra)
+	assert.Equal(t, recs[2], pr[2])
+}
+
+func TestServerProctoredAssessment(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	recs := placementRecords(3, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	recs[1].ProctoredAssessment = aleks.ProctoredAssessment{Proctored: aleks.ProctoredYes, Status: aleks.AssessmentExpired}
+	recs[2].ProctoredAssessment = aleks.ProctoredAssessment{Raw: "No/Abandoned", Proctored: aleks.ProctoredNo}
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	pr, errs := c.GetPlacementReport("2016-03-01", "2016-03-03", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	require.Len(t, pr, 3)
+	sort.Slice(pr, func(i, j int) bool { return pr[i].StudentID < pr[j].StudentID })
+	assert.Equal(t, recs[0], pr[0])
+	assert.Equal(t, aleks.ProctoredAssessment{Raw: "Yes/Expired", Proctored: aleks.ProctoredYes, Status: aleks.AssessmentExpired}, pr[1].ProctoredAssessment)
+	assert.Empty(t, pr[1].Warnings)
+	assert.Equal(t, recs[2].ProctoredAssessment, pr[2].ProctoredAssessment)
+	require.Len(t, pr[2].Warnings, 1)
+	assert.Contains(t, pr[2].Warnings[0].Error(), "unknown assessment status: Abandoned")
+}
+
+func TestServerLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	srv.Location = ny
+	// 2016-03-13T03:30:00Z is 11:30 PM on March 12th in New York
+	recs := placementRecords(1, time.Date(2016, time.March, 13, 0, 0, 0, 0, time.UTC))
+	recs[0].StartTime = time.Date(2016, time.March, 13, 1, 0, 0, 0, time.UTC)
+	recs[0].EndTime = time.Date(2016, time.March, 13, 3, 30, 0, 0, time.UTC)
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password", aleks.WithLocation(ny))
+	require.NoError(t, err)
+	pr, errs := c.GetPlacementReport("2016-03-12", "2016-03-12", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	require.Len(t, pr, 1)
+	assert.True(t, recs[0].StartTime.Equal(pr[0].StartTime), pr[0].StartTime.String())
+	assert.True(t, recs[0].EndTime.Equal(pr[0].EndTime), pr[0].EndTime.String())
+
+	// Interpreting the local times as UTC shifts them by five hours
+	c, err = aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	pr, errs = c.GetPlacementReport("2016-03-12", "2016-03-12", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	require.Len(t, pr, 1)
+	assert.Equal(t, -5*time.Hour, pr[0].EndTime.Sub(recs[0].EndTime))
+}
+
+func TestServerSpecialCharacters(t *testing.T) {
+	srv := alekstest.NewServer("username", "password")
+	defer srv.Close()
+	recs := placementRecords(3, time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC))
+	recs[0].Name = "Smith & Jones"
+	recs[1].Name = "<Doe>, \"Jane\""
+	recs[2].Name = "Doe]]>, John"
+	srv.AddPlacementRecords("ABCDE-FGHIJ", recs...)
+
+	c, err := aleks.NewClient(srv.URL, "username", "password")
+	require.NoError(t, err)
+	pr, errs := c.GetPlacementReport("2016-01-01", "2016-12-31", "ABCDE-FGHIJ")
+	require.Len(t, errs, 0)
+	assert.Equal(t, aleks.PlacementReport(recs), pr)
+}